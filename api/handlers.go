@@ -0,0 +1,334 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/matt-g-everett/ledtx/stream"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type statusResponse struct {
+	stream.ControllerStatus
+	Frames stream.FrameStats `json:"frames"`
+	Watts  float64           `json:"watts"`
+}
+
+type animationResponse struct {
+	Current stream.AnimationInfo  `json:"current"`
+	Next    *stream.AnimationInfo `json:"next"`
+}
+
+type animationsResponse struct {
+	Types       []stream.AnimationType `json:"types"`
+	Presets     []string               `json:"presets"`
+	Transitions []string               `json:"transitions"`
+	Easings     []string               `json:"easings"`
+}
+
+type jumpRequest struct {
+	Name string `json:"name"`
+}
+
+type cyclingRequest struct {
+	Cycling bool `json:"cycling"`
+}
+
+type playlistRequest struct {
+	Name string `json:"name"`
+}
+
+type playlistResponse struct {
+	Playlist  string   `json:"playlist"`
+	Playlists []string `json:"playlists"`
+}
+
+type saturationRequest struct {
+	Mode string `json:"mode"`
+}
+
+type saturationResponse struct {
+	Mode  string   `json:"mode"`
+	Modes []string `json:"modes"`
+}
+
+type favouriteRequest struct {
+	Name string `json:"name"`
+}
+
+type favouritesResponse struct {
+	Favourites map[string]stream.Favourite `json:"favourites"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write API response. %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// allowMethod checks the request method and responds with an error if it isn't allowed.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return false
+	}
+
+	return true
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+
+	return true
+}
+
+func (a *Api) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, statusResponse{
+		ControllerStatus: a.streamer.Controller().Status(),
+		Frames:           a.streamer.Stats(),
+		Watts:            a.streamer.Watts(),
+	})
+}
+
+func (a *Api) writeAnimation(w http.ResponseWriter) {
+	status := a.streamer.Controller().Status()
+	writeJSON(w, http.StatusOK, animationResponse{Current: status.Current, Next: status.Next})
+}
+
+func (a *Api) handleAnimation(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	a.writeAnimation(w)
+}
+
+func (a *Api) handleNext(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	if err := a.streamer.Controller().Next(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	a.writeAnimation(w)
+}
+
+func (a *Api) handlePrevious(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	if err := a.streamer.Controller().Previous(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	a.writeAnimation(w)
+}
+
+func (a *Api) handleJump(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req jumpRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if err := a.streamer.Controller().Jump(req.Name); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	a.writeAnimation(w)
+}
+
+func (a *Api) handleAnimations(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, animationsResponse{
+		Types:       stream.AnimationTypes(),
+		Presets:     stream.AnimationPresets(),
+		Transitions: stream.TransitionStyles(),
+		Easings:     stream.Easings(),
+	})
+}
+
+func (a *Api) handleCycling(w http.ResponseWriter, r *http.Request) {
+	controller := a.streamer.Controller()
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req cyclingRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+
+		if err := controller.SetCycling(req.Cycling); err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cyclingRequest{Cycling: controller.Status().Cycling})
+}
+
+func (a *Api) handlePlaylist(w http.ResponseWriter, r *http.Request) {
+	controller := a.streamer.Controller()
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req playlistRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+
+		if err := controller.SetPlaylist(req.Name); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	status := controller.Status()
+	writeJSON(w, http.StatusOK, playlistResponse{Playlist: status.Playlist, Playlists: status.Playlists})
+}
+
+func (a *Api) handleSaturation(w http.ResponseWriter, r *http.Request) {
+	controller := a.streamer.Controller()
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req saturationRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+
+		if err := controller.SetSaturationMode(req.Mode); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, saturationResponse{Mode: controller.Status().Saturation, Modes: stream.SaturationModes()})
+}
+
+func (a *Api) writeFavourites(w http.ResponseWriter, status int) {
+	favourites := a.streamer.Favourites()
+	response := favouritesResponse{Favourites: make(map[string]stream.Favourite)}
+	for _, name := range favourites.Names() {
+		response.Favourites[name], _ = favourites.Get(name)
+	}
+
+	writeJSON(w, status, response)
+}
+
+// handleFavourites lists the favourites, or saves the current animation as a favourite.
+func (a *Api) handleFavourites(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.writeFavourites(w, http.StatusOK)
+	case http.MethodPost:
+		var req favouriteRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+
+		current := a.streamer.Controller().Status().Current
+		if _, err := a.streamer.Favourites().Save(req.Name, current); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		a.writeFavourites(w, http.StatusCreated)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+	}
+}
+
+func (a *Api) handleFavouriteRecall(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req favouriteRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	favourite, ok := a.streamer.Favourites().Get(req.Name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown favourite %q", req.Name))
+		return
+	}
+
+	if err := a.streamer.Controller().PlayFavourite(req.Name, favourite); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	a.writeAnimation(w)
+}
+
+func (a *Api) handleCalibrationStart(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	if err := a.streamer.Calibrate().Start(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (a *Api) handleCalibrationStop(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	if err := a.streamer.Calibrate().Stop(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
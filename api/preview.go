@@ -0,0 +1,144 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/matt-g-everett/ledtx/stream"
+)
+
+const previewWriteWait = 5 * time.Second
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// previewClient is a browser that's watching the preview, it's sent frames no faster than its interval.
+type previewClient struct {
+	conn     *websocket.Conn
+	interval time.Duration
+	lastSent time.Time
+	send     chan []byte
+}
+
+// previewHub fans rendered frames out to the connected preview clients.
+type previewHub struct {
+	clients map[*previewClient]bool
+	lock    sync.Mutex
+}
+
+func newPreviewHub() *previewHub {
+	h := new(previewHub)
+	h.clients = make(map[*previewClient]bool)
+	return h
+}
+
+// frame is a stream.FrameListener, clients that are still busy with the last frame miss this one.
+func (h *previewHub) frame(f *stream.Frame) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if len(h.clients) == 0 {
+		return
+	}
+
+	now := time.Now()
+	var data []byte
+	for c := range h.clients {
+		if now.Sub(c.lastSent) < c.interval {
+			continue
+		}
+
+		if data == nil {
+			data = f.RGB()
+		}
+
+		select {
+		case c.send <- data:
+			c.lastSent = now
+		default:
+		}
+	}
+}
+
+func (h *previewHub) add(c *previewClient) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.clients[c] = true
+}
+
+func (h *previewHub) remove(c *previewClient) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.clients[c] {
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
+
+func (h *previewHub) writeFrames(c *previewClient) {
+	defer c.conn.Close()
+	for data := range c.send {
+		c.conn.SetWriteDeadline(time.Now().Add(previewWriteWait))
+		if err := c.conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+			log.Printf("Dropping preview client. %s", err)
+			h.remove(c)
+			return
+		}
+	}
+}
+
+// readUntilClosed discards anything the browser sends so that the close is noticed.
+func (h *previewHub) readUntilClosed(c *previewClient) {
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			h.remove(c)
+			return
+		}
+	}
+}
+
+// handlePreview streams frames as binary RGB triplets, the fps query parameter reduces the frame rate.
+func (a *Api) handlePreview(w http.ResponseWriter, r *http.Request) {
+	interval := time.Duration(0)
+	if fps := r.URL.Query().Get("fps"); fps != "" {
+		rate, err := strconv.ParseFloat(fps, 64)
+		if err != nil || rate <= 0 {
+			http.Error(w, "fps should be a positive number", http.StatusBadRequest)
+			return
+		}
+		interval = time.Duration(float64(time.Second) / rate)
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade preview connection. %s", err)
+		return
+	}
+
+	c := &previewClient{conn: conn, interval: interval, send: make(chan []byte, 1)}
+	a.preview.add(c)
+	go a.preview.writeFrames(c)
+	go a.preview.readUntilClosed(c)
+}
+
+// handlePixels serves the calibrated pixel locations so that the preview can draw the tree's shape.
+func (a *Api) handlePixels(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	if _, err := os.Stat(stream.ResolvedPixelsPath); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	http.ServeFile(w, r, stream.ResolvedPixelsPath)
+}
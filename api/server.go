@@ -1,23 +1,54 @@
 package api
 
 import (
-    "log"
-    "net/http"
+	"log"
+	"net/http"
+
+	"github.com/matt-g-everett/ledtx/stream"
 )
 
 type Api struct {
-
+	streamer *stream.Streamer
+	preview  *previewHub
+	mux      *http.ServeMux
 }
 
-func NewApi() *Api {
-    a := new(Api)
-    return a
+func NewApi(streamer *stream.Streamer) *Api {
+	a := new(Api)
+	a.streamer = streamer
+	a.preview = newPreviewHub()
+	a.streamer.AddFrameListener(a.preview.frame)
+	a.mux = http.NewServeMux()
+
+	a.mux.HandleFunc("/api/status", a.handleStatus)
+	a.mux.HandleFunc("/api/animation", a.handleAnimation)
+	a.mux.HandleFunc("/api/animation/next", a.handleNext)
+	a.mux.HandleFunc("/api/animation/previous", a.handlePrevious)
+	a.mux.HandleFunc("/api/animation/jump", a.handleJump)
+	a.mux.HandleFunc("/api/animations", a.handleAnimations)
+	a.mux.HandleFunc("/api/cycling", a.handleCycling)
+	a.mux.HandleFunc("/api/playlist", a.handlePlaylist)
+	a.mux.HandleFunc("/api/saturation", a.handleSaturation)
+	a.mux.HandleFunc("/api/favourites", a.handleFavourites)
+	a.mux.HandleFunc("/api/favourites/recall", a.handleFavouriteRecall)
+	a.mux.HandleFunc("/api/calibration/start", a.handleCalibrationStart)
+	a.mux.HandleFunc("/api/calibration/stop", a.handleCalibrationStop)
+	a.mux.HandleFunc("/api/preview", a.handlePreview)
+	a.mux.HandleFunc("/api/pixels", a.handlePixels)
+
+	fs := http.FileServer(http.Dir("client/dist"))
+	a.mux.Handle("/", fs)
+
+	return a
 }
 
-func (*Api) Serve() {
-    fs := http.FileServer(http.Dir("client/dist"))
-    http.Handle("/", fs)
+func (a *Api) Serve() {
+	log.Println("Listening...")
+	if err := http.ListenAndServe(":3000", a); err != nil {
+		log.Printf("API server stopped. %s", err)
+	}
+}
 
-    log.Println("Listening...")
-    http.ListenAndServe(":3000", nil)
+func (a *Api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mux.ServeHTTP(w, r)
 }
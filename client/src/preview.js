@@ -0,0 +1,96 @@
+// Draws the frames from the ledtx preview WebSocket. Pixels are drawn at their calibrated positions when
+// caldata/resolved.json is available, otherwise as a strip.
+
+const PREVIEW_FPS = 20;
+const PIXEL_SIZE = 6;
+const STRIP_COLUMNS = 50;
+
+let locations = null;
+
+function loadLocations() {
+    return fetch('/api/pixels')
+        .then(response => response.ok ? response.json() : null)
+        .then(pixels => {
+            if (pixels) {
+                locations = pixels;
+            }
+        })
+        .catch(() => {
+            locations = null;
+        });
+}
+
+function calibratedBounds() {
+    let minX = Infinity, minY = Infinity, maxX = -Infinity, maxY = -Infinity;
+    locations.forEach(p => {
+        if (p.resolved) {
+            minX = Math.min(minX, p.loc.x);
+            minY = Math.min(minY, p.loc.y);
+            maxX = Math.max(maxX, p.loc.x);
+            maxY = Math.max(maxY, p.loc.y);
+        }
+    });
+
+    return { minX, minY, maxX, maxY };
+}
+
+function drawCalibrated(ctx, canvas, rgb) {
+    const bounds = calibratedBounds();
+    const scale = Math.min(
+        (canvas.width - PIXEL_SIZE * 2) / Math.max(bounds.maxX - bounds.minX, 1),
+        (canvas.height - PIXEL_SIZE * 2) / Math.max(bounds.maxY - bounds.minY, 1));
+
+    for (let i = 0; i < locations.length && i * 3 < rgb.length; i++) {
+        const p = locations[i];
+        if (!p.resolved) {
+            continue;
+        }
+
+        const x = PIXEL_SIZE + (p.loc.x - bounds.minX) * scale;
+        const y = PIXEL_SIZE + (p.loc.y - bounds.minY) * scale;
+        ctx.fillStyle = `rgb(${rgb[i * 3]}, ${rgb[i * 3 + 1]}, ${rgb[i * 3 + 2]})`;
+        ctx.beginPath();
+        ctx.arc(x, y, PIXEL_SIZE / 2, 0, Math.PI * 2);
+        ctx.fill();
+    }
+}
+
+function drawStrip(ctx, canvas, rgb) {
+    const pixelCount = rgb.length / 3;
+    const cell = canvas.width / STRIP_COLUMNS;
+    for (let i = 0; i < pixelCount; i++) {
+        const x = (i % STRIP_COLUMNS) * cell;
+        const y = Math.floor(i / STRIP_COLUMNS) * cell;
+        ctx.fillStyle = `rgb(${rgb[i * 3]}, ${rgb[i * 3 + 1]}, ${rgb[i * 3 + 2]})`;
+        ctx.fillRect(x + 1, y + 1, cell - 2, cell - 2);
+    }
+}
+
+function connect(canvas) {
+    const ctx = canvas.getContext('2d');
+    const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
+    const socket = new WebSocket(`${protocol}//${window.location.host}/api/preview?fps=${PREVIEW_FPS}`);
+    socket.binaryType = 'arraybuffer';
+
+    socket.onmessage = event => {
+        const rgb = new Uint8Array(event.data);
+        ctx.fillStyle = 'black';
+        ctx.fillRect(0, 0, canvas.width, canvas.height);
+
+        if (locations && locations.length > 0) {
+            drawCalibrated(ctx, canvas, rgb);
+        } else {
+            drawStrip(ctx, canvas, rgb);
+        }
+    };
+
+    // Keep trying while ledtx restarts
+    socket.onclose = () => {
+        setTimeout(() => connect(canvas), 2000);
+    };
+}
+
+window.onload = () => {
+    const canvas = document.getElementById('preview');
+    loadLocations().then(() => connect(canvas));
+}
@@ -5,6 +5,7 @@ go 1.16
 require (
 	github.com/eclipse/paho.mqtt.golang v1.3.5
 	github.com/fogleman/ease v0.0.0-20170301025033-8da417bf1776
+	github.com/gorilla/websocket v1.4.2
 	github.com/lucasb-eyer/go-colorful v1.2.0
 	gopkg.in/yaml.v2 v2.4.0
 )
@@ -3,8 +3,9 @@ package main
 import (
 	"flag"
 	"log"
-	"math/rand"
+	"math"
 	"os"
+	"path/filepath"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
@@ -26,16 +27,83 @@ func newApp() *app {
 
 func (a *app) handleOnConnect(client mqtt.Client) {
 	log.Println("Connected")
-	a.Streamer.Subscribe()
+	if a.Streamer != nil {
+		a.Streamer.Subscribe()
+	}
 }
 
-func (a *app) run() {
+func (a *app) connect() {
 	if token := a.Client.Connect(); token.Wait() && token.Error() != nil {
 		panic(token.Error())
 	}
+}
+
+func (a *app) run() {
+	a.connect()
 	a.Streamer.Run()
 }
 
+func (a *app) replay(recordingPath string) {
+	replayer, err := stream.NewReplayer(recordingPath)
+	if err != nil {
+		panic(err)
+	}
+
+	layout, err := stream.NewLayout(a.Config.Layout)
+	if err != nil {
+		panic(err)
+	}
+
+	transport, err := stream.NewTransport(a.Config, a.Client, layout)
+	if err != nil {
+		panic(err)
+	}
+
+	a.connect()
+	if err := replayer.Run(transport); err != nil {
+		panic(err)
+	}
+}
+
+// analyseCalibration resolves recorded calibration data again with different settings, it doesn't need MQTT.
+func (a *app) analyseCalibration(args []string) {
+	defaults := stream.DefaultCalibrationOptions(stream.Config{})
+	flags := flag.NewFlagSet("calibrate analyse", flag.ExitOnError)
+	configPath := flags.String("config", "config.yaml", "YAML config file with the calibration strategy.")
+	dir := flags.String("dir", stream.CalibrationDir, "Directory with the raw calibration data.")
+	out := flags.String("out", "", "Directory to write the results to, defaults to analysis in the data directory.")
+	strategy := flags.String("strategy", "", "Calibration strategy that captured the data, defaults to the config's.")
+	distance := flags.Float64("distance", defaults.SimilarityDistance, "Points closer together than this are in the same bin.")
+	hits := flags.Int("hits", int(defaults.HitThreshold), "Bins seen fewer times than this are ignored.")
+	flags.Parse(args)
+
+	if !(*distance > 0) {
+		log.Fatalf("Usage: --distance should be more than 0, not %g", *distance)
+	}
+	if *hits < 1 || *hits > math.MaxInt32 {
+		log.Fatalf("Usage: --hits should be between 1 and %d, not %d", math.MaxInt32, *hits)
+	}
+
+	// Don't replace the pixel map that the lights are using unless asked to
+	if *out == "" {
+		*out = filepath.Join(*dir, "analysis")
+	}
+
+	a.readConfig(*configPath)
+	options := stream.DefaultCalibrationOptions(a.Config)
+	if *strategy != "" {
+		options.Strategy = *strategy
+	}
+	options.SimilarityDistance = *distance
+	options.HitThreshold = int32(*hits)
+	summary, err := stream.AnalyseCalibration(*dir, *out, options)
+	if err != nil {
+		panic(err)
+	}
+
+	log.Printf("Calibration %s", summary)
+}
+
 func (a *app) readConfig(configPath string) {
 	f, err := os.Open(configPath)
 	if err != nil {
@@ -53,12 +121,20 @@ func main() {
 	// mqtt.DEBUG = log.New(os.Stdout, "", 0)
 	mqtt.ERROR = log.New(os.Stdout, "", 0)
 
+	if len(os.Args) > 1 && os.Args[1] == "calibrate" {
+		if len(os.Args) < 3 || os.Args[2] != "analyse" {
+			log.Fatal("Usage: ledtx calibrate analyse [--config config.yaml] [--dir caldata] [--out caldata/analysis] [--strategy primes] [--distance 4] [--hits 1]")
+		}
+		newApp().analyseCalibration(os.Args[3:])
+		return
+	}
+
 	// Parse command line parameters
 	configPath := flag.String("config", "config.yaml", "YAML config file.")
+	recordDir := flag.String("record", "", "Directory to record the frames that are sent to.")
+	replayPath := flag.String("replay", "", "Recording file to replay instead of running animations.")
 	flag.Parse()
 
-	rand.Seed(time.Now().UTC().UnixNano())
-
 	// Read the config
 	a := newApp()
 	a.readConfig(*configPath)
@@ -72,12 +148,33 @@ func main() {
 		SetKeepAlive(30 * time.Second).
 		SetPingTimeout(5 * time.Second).
 		SetOnConnectHandler(a.handleOnConnect)
+	if a.Config.Mqtt.Topics.Availability != "" {
+		// The broker tells everyone that we're offline if we disconnect without saying goodbye
+		options.SetWill(a.Config.Mqtt.Topics.Availability, stream.AvailabilityOffline, 0, true)
+	}
 	client := mqtt.NewClient(options)
 
 	a.Client = client
-	a.Streamer = stream.NewStreamer(a.Config, client)
+	if *replayPath != "" {
+		a.replay(*replayPath)
+		return
+	}
+
+	streamer, err := stream.NewStreamer(a.Config, client)
+	if err != nil {
+		panic(err)
+	}
+	a.Streamer = streamer
+
+	if *recordDir != "" {
+		recorder, err := stream.NewRecorder(*recordDir)
+		if err != nil {
+			panic(err)
+		}
+		a.Streamer.SetRecorder(recorder)
+	}
 
-	api := api.NewApi()
+	api := api.NewApi(a.Streamer)
 	go api.Serve()
 
 	a.run()
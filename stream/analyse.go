@@ -0,0 +1,281 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// CalibrationOptions are the settings used to work out where the pixels are from the captured data.
+type CalibrationOptions struct {
+	Strategy           string
+	SimilarityDistance float64 // Points closer together than this go in the same bin
+	HitThreshold       int32   // Bins that were seen fewer times than this are ignored
+}
+
+// DefaultCalibrationOptions gets the settings that a live calibration uses.
+func DefaultCalibrationOptions(config Config) CalibrationOptions {
+	return CalibrationOptions{
+		Strategy:           config.Calibration.Strategy,
+		SimilarityDistance: binSimilarityDistance,
+		HitThreshold:       binHitThreshold,
+	}
+}
+
+// A CalibrationSummary counts the pixels that calibration found. Ambiguous pixels could have been in a bin
+// but another pixel was just as likely, missing pixels weren't a candidate for any bin.
+type CalibrationSummary struct {
+	Pixels    int `json:"pixels"`
+	Bins      int `json:"bins"`
+	HitBins   int `json:"hitBins"`
+	Resolved  int `json:"resolved"`
+	Ambiguous int `json:"ambiguous"`
+	Missing   int `json:"missing"`
+	Outliers  int `json:"outliers"`
+}
+
+func (s CalibrationSummary) String() string {
+	return fmt.Sprintf("resolved %d of %d pixels, %d ambiguous, %d missing, %d outliers (%d bins, %d with enough hits)",
+		s.Resolved, s.Pixels, s.Ambiguous, s.Missing, s.Outliers, s.Bins, s.HitBins)
+}
+
+// calibrationData is the points that the camera saw, gathered into bins of nearby points.
+type calibrationData struct {
+	raw        []*RawCalibrationData
+	aggregated *AggregatedData
+	index      *binGrid
+	minHits    int32
+
+	lock sync.RWMutex
+}
+
+func newCalibrationData(raw []*RawCalibrationData, similarityDistance float64, minHits int32) *calibrationData {
+	d := new(calibrationData)
+	d.raw = raw
+	d.aggregated = &AggregatedData{Bins: make([]*Bin, 0, 5000)}
+	d.index = newBinGrid(similarityDistance)
+	d.minHits = minHits
+
+	return d
+}
+
+func (d *calibrationData) aggregate() {
+	// var wg sync.WaitGroup
+	for _, r := range d.raw {
+		for _, l := range r.Locations {
+			//wg.Add(len(r.Locations))
+			d.incrementBin(l, r.Pixels)
+		}
+	}
+
+	//wg.Wait()
+}
+
+func (d *calibrationData) doIncrementBin(bin *Bin, lit []int32) {
+	atomic.AddInt32(&bin.Hits, 1)
+	for j := 0; j < len(lit); j++ {
+		atomic.AddInt32(&bin.Pixels[j], lit[j])
+	}
+}
+
+func (d *calibrationData) incrementBin(binLocation Point, lit []int32) {
+	d.lock.RLock()
+	found := d.index.find(d.aggregated.Bins, binLocation)
+	d.lock.RUnlock()
+
+	if found < 0 {
+		d.lock.Lock()
+		// Search again, another goroutine may have beaten us to it
+		found = d.index.find(d.aggregated.Bins, binLocation)
+
+		// If the bin is still not present while we're locked, add it
+		if found < 0 {
+			litCopy := make([]int32, len(lit))
+			copy(litCopy, lit)
+			d.index.add(binLocation, len(d.aggregated.Bins))
+			d.aggregated.Bins = append(d.aggregated.Bins, &Bin{Location: binLocation, Pixels: litCopy, Hits: 1})
+		}
+		d.lock.Unlock()
+
+		// A new bin starts with this point's counts, otherwise another goroutine added it so increment as usual
+		if found < 0 {
+			return
+		}
+	}
+
+	d.doIncrementBin(d.aggregated.Bins[found], lit)
+}
+
+// hitBins gets the bins that were seen often enough to be used.
+func (d *calibrationData) hitBins() *AggregatedData {
+	highHitData := &AggregatedData{Bins: make([]*Bin, 0, len(d.aggregated.Bins))}
+	for _, b := range d.aggregated.Bins {
+		if b.Hits >= d.minHits {
+			highHitData.Bins = append(highHitData.Bins, b)
+		}
+	}
+
+	return highHitData
+}
+
+// resolveCalibration works out where each pixel is from the captured data and writes the bins, the resolved
+// and interpolated pixels and a summary to a directory.
+func resolveCalibration(raw []*RawCalibrationData, pixelCount int, options CalibrationOptions,
+	dir string) ([]Pixel, CalibrationSummary, error) {
+
+	strategy, err := newCalibrationStrategy(options.Strategy)
+	if err != nil {
+		return nil, CalibrationSummary{}, err
+	}
+
+	d := newCalibrationData(raw, options.SimilarityDistance, options.HitThreshold)
+	d.aggregate()
+	highHitData := d.hitBins()
+
+	resolved := make([]Pixel, pixelCount, pixelCount)
+	ambiguous := strategy.resolve(d, resolved)
+	interpolated := InterpolatePixels(resolved, pixelCount)
+
+	summary := CalibrationSummary{Pixels: pixelCount, Bins: len(d.aggregated.Bins), HitBins: len(highHitData.Bins)}
+	for i := range resolved {
+		switch {
+		case resolved[i].Resolved:
+			summary.Resolved++
+		case ambiguous[i]:
+			summary.Ambiguous++
+		default:
+			summary.Missing++
+		}
+
+		if interpolated[i].Outlier {
+			summary.Outliers++
+		}
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"aggregated_raw.json", d.aggregated},
+		{"aggregated.json", highHitData},
+		{filepath.Base(ResolvedPixelsPath), resolved},
+		{filepath.Base(InterpolatedPixelsPath), interpolated},
+		{"summary.json", summary},
+	}
+	for _, f := range files {
+		if err := writeCalibrationFile(f.data, filepath.Join(dir, f.name)); err != nil {
+			return nil, summary, err
+		}
+	}
+
+	return resolved, summary, nil
+}
+
+// AnalyseCalibration resolves the raw data from an earlier calibration again, so that the options can be tuned
+// without capturing it again. The results are written to the out directory.
+func AnalyseCalibration(dir string, out string, options CalibrationOptions) (CalibrationSummary, error) {
+	raw, err := loadRawCalibrationData(dir)
+	if err != nil {
+		return CalibrationSummary{}, err
+	}
+
+	if err := checkCalibrationFrames(raw, options.Strategy); err != nil {
+		return CalibrationSummary{}, err
+	}
+
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return CalibrationSummary{}, err
+	}
+
+	_, summary, err := resolveCalibration(raw, len(raw[0].Pixels), options, out)
+	return summary, err
+}
+
+// checkCalibrationFrames makes sure that the raw data was captured with a strategy, the frames that it shows
+// depend on the strategy so decoding them with another one gives nonsense.
+func checkCalibrationFrames(raw []*RawCalibrationData, strategyName string) error {
+	strategy, err := newCalibrationStrategy(strategyName)
+	if err != nil {
+		return err
+	}
+
+	frames := strategy.frames(len(raw[0].Pixels))
+	captured := make(map[int]bool)
+	for _, r := range raw {
+		if r.Frame < 0 || r.Frame >= len(frames) || !equalLit(r.Pixels, frames[r.Frame].lit) {
+			return fmt.Errorf("raw frame %d doesn't match the %q calibration strategy", r.Frame, strategyName)
+		}
+		captured[r.Frame] = true
+	}
+
+	if len(captured) != len(frames) {
+		return fmt.Errorf("the raw data has %d frames but the %q calibration strategy shows %d", len(captured),
+			strategyName, len(frames))
+	}
+
+	return nil
+}
+
+func equalLit(a []int32, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// loadRawCalibrationData reads the raw files in capture order.
+func loadRawCalibrationData(dir string) ([]*RawCalibrationData, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "raw", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("there's no raw calibration data in %s", dir)
+	}
+
+	sort.Strings(paths)
+	raw := make([]*RawCalibrationData, 0, len(paths))
+	for _, path := range paths {
+		r := new(RawCalibrationData)
+		if err := readJSON(path, r); err != nil {
+			return nil, err
+		}
+
+		if len(raw) > 0 && len(r.Pixels) != len(raw[0].Pixels) {
+			return nil, fmt.Errorf("%s has %d pixels but the others have %d", path, len(r.Pixels), len(raw[0].Pixels))
+		}
+		raw = append(raw, r)
+	}
+
+	log.Printf("Read %d raw calibration files from %s", len(raw), dir)
+	return raw, nil
+}
+
+func writeCalibrationFile(data interface{}, filePath string) error {
+	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0664)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	serialised, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(serialised)
+	return err
+}
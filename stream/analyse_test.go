@@ -0,0 +1,29 @@
+package stream
+
+import "testing"
+
+func TestCheckCalibrationFrames(t *testing.T) {
+	// Use the recording in testdata, which was captured with primes
+	raw, err := loadRawCalibrationData("testdata/caldata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkCalibrationFrames(raw, "primes"); err != nil {
+		t.Errorf("the primes data should match the primes strategy: %s", err)
+	}
+
+	if err := checkCalibrationFrames(raw, "gray"); err == nil {
+		t.Error("the primes data shouldn't match the gray strategy")
+	}
+
+	var missing []*RawCalibrationData
+	for _, r := range raw {
+		if r.Frame != 3 {
+			missing = append(missing, r)
+		}
+	}
+	if err := checkCalibrationFrames(missing, "primes"); err == nil {
+		t.Error("data that's missing a frame shouldn't match")
+	}
+}
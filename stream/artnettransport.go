@@ -0,0 +1,110 @@
+package stream
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+const (
+	artNetPort            = 6454
+	artNetHeaderLength    = 18
+	artNetOpDmx           = 0x5000
+	artNetProtocolVersion = 14
+	artNetMaxUniverse     = 0x7fff
+)
+
+var artNetID = []byte("Art-Net\x00")
+
+// ArtNetTransport sends frames as ArtDmx packets, one per universe, to a unicast or broadcast address.
+// Art-Net universes are the 15-bit port address, numbered from 0. Art-Net has no way to ACK frames.
+type ArtNetTransport struct {
+	conn      net.PacketConn
+	addr      net.Addr
+	universes dmxUniverses
+	packet    []byte
+}
+
+// NewArtNetTransport creates an instance of an ArtNetTransport.
+func NewArtNetTransport(config Config, layout *Layout) (*ArtNetTransport, error) {
+	universes, err := newDmxUniverses(config.Transport.Dmx.Universes, layout, dmxDefaultArtNetStart)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range universes {
+		if u.universe > artNetMaxUniverse {
+			return nil, fmt.Errorf("universe %d is out of range for Art-Net", u.universe)
+		}
+	}
+
+	address := config.Transport.Dmx.Address
+	if address == "" {
+		return nil, fmt.Errorf("Art-Net needs an address to send to")
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, fmt.Sprint(artNetPort))
+	}
+
+	addr, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+
+	t := new(ArtNetTransport)
+	t.conn = conn
+	t.addr = addr
+	t.universes = universes
+	t.packet = make([]byte, artNetHeaderLength+dmxChannels)
+
+	// Start at 1 because a sequence of 0 tells the receiver not to reorder packets
+	for _, u := range t.universes {
+		u.sequence = 1
+	}
+
+	return t, nil
+}
+
+func (t *ArtNetTransport) buildPacket(u *dmxUniverse) []byte {
+	p := t.packet
+	copy(p[0:8], artNetID)
+	binary.LittleEndian.PutUint16(p[8:], artNetOpDmx)
+	binary.BigEndian.PutUint16(p[10:], artNetProtocolVersion)
+	p[12] = u.sequence
+	p[13] = 0                          // Physical port
+	p[14] = byte(u.universe)           // SubUni
+	p[15] = byte(u.universe>>8) & 0x7f // Net
+	binary.BigEndian.PutUint16(p[16:], dmxChannels)
+	copy(p[artNetHeaderLength:], u.data[:])
+
+	return p
+}
+
+// Send splits a frame into universes and sends an ArtDmx packet for each one.
+func (t *ArtNetTransport) Send(f *Frame) error {
+	if err := t.universes.fill(f.RGB()); err != nil {
+		return err
+	}
+
+	for _, u := range t.universes {
+		if _, err := t.conn.WriteTo(t.buildPacket(u), t.addr); err != nil {
+			return err
+		}
+
+		u.sequence++
+		if u.sequence == 0 {
+			u.sequence = 1
+		}
+	}
+
+	return nil
+}
+
+// SetAckHandler does nothing because Art-Net doesn't support ACKs.
+func (t *ArtNetTransport) SetAckHandler(handler AckHandler) {
+}
@@ -0,0 +1,60 @@
+package stream
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestArtNetPacketLayout(t *testing.T) {
+	conn, address := listenUDP(t)
+	layout, f := testFrame(t, 200)
+
+	var config Config
+	config.Transport.Dmx.Address = address
+	transport, err := NewArtNetTransport(config, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := transport.Send(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	packets := receivePackets(t, conn, 4)
+
+	for i, p := range packets {
+		if len(p) != artNetHeaderLength+dmxChannels {
+			t.Fatalf("packet %d is %d bytes", i, len(p))
+		}
+		if string(p[0:8]) != string(artNetID) {
+			t.Errorf("packet %d has ID %q", i, p[0:8])
+		}
+		if op := binary.LittleEndian.Uint16(p[8:]); op != artNetOpDmx {
+			t.Errorf("packet %d has opcode %#x", i, op)
+		}
+		if version := binary.BigEndian.Uint16(p[10:]); version != artNetProtocolVersion {
+			t.Errorf("packet %d has protocol version %d", i, version)
+		}
+		if length := binary.BigEndian.Uint16(p[16:]); length != dmxChannels {
+			t.Errorf("packet %d has length %d", i, length)
+		}
+	}
+
+	for i, want := range []struct {
+		universe   uint16
+		sequence   uint8
+		firstPixel byte
+	}{{0, 1, 0}, {1, 1, 170}, {0, 2, 0}, {1, 2, 170}} {
+		p := packets[i]
+		if universe := uint16(p[14]) | uint16(p[15])<<8; universe != want.universe {
+			t.Errorf("packet %d is for universe %d, expected %d", i, universe, want.universe)
+		}
+		if p[12] != want.sequence {
+			t.Errorf("packet %d has sequence %d, expected %d", i, p[12], want.sequence)
+		}
+		if p[artNetHeaderLength] != want.firstPixel {
+			t.Errorf("packet %d starts with pixel %d, expected %d", i, p[artNetHeaderLength], want.firstPixel)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package stream
+
+import "math"
+
+// binCell is a square in a binGrid.
+type binCell struct {
+	x int64
+	y int64
+}
+
+// A binGrid finds calibration bins by location. Bins are hashed into square cells as wide as the similarity
+// distance, so a point only needs comparing with the bins in the cells around it instead of every bin.
+type binGrid struct {
+	size  float64
+	cells map[binCell][]int
+}
+
+func newBinGrid(size float64) *binGrid {
+	g := new(binGrid)
+	g.size = size
+	g.cells = make(map[binCell][]int)
+
+	return g
+}
+
+func (g *binGrid) cell(p Point) binCell {
+	return binCell{x: int64(math.Floor(p.X / g.size)), y: int64(math.Floor(p.Y / g.size))}
+}
+
+// add records that bins[index] is at a location.
+func (g *binGrid) add(location Point, index int) {
+	cell := g.cell(location)
+	g.cells[cell] = append(g.cells[cell], index)
+}
+
+// find gets the index of the first bin that was added within the similarity distance of a point, which is
+// the same bin that scanning every bin in order finds. It's -1 if there isn't one.
+func (g *binGrid) find(bins []*Bin, p Point) int {
+	centre := g.cell(p)
+	found := -1
+	for x := centre.x - 1; x <= centre.x+1; x++ {
+		for y := centre.y - 1; y <= centre.y+1; y++ {
+			for _, i := range g.cells[binCell{x: x, y: y}] {
+				// Indexes in a cell are in the order they were added
+				if found >= 0 && i > found {
+					break
+				}
+				if isBin(p, bins[i].Location, g.size) {
+					found = i
+					break
+				}
+			}
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,107 @@
+package stream
+
+import (
+	"os"
+	"testing"
+)
+
+// rawCalibrationDir is the recorded calibration used by the tests, set LEDTX_CALDATA to use a real recording
+// instead of the small one in testdata.
+func rawCalibrationDir() string {
+	if dir := os.Getenv("LEDTX_CALDATA"); dir != "" {
+		return dir
+	}
+
+	return "testdata/caldata"
+}
+
+func loadTestCalibration(tb testing.TB) []*RawCalibrationData {
+	raw, err := loadRawCalibrationData(rawCalibrationDir())
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return raw
+}
+
+// linearFind is how bins were found before the grid, by scanning every bin in order.
+func linearFind(bins []*Bin, p Point) int {
+	for i, b := range bins {
+		if isBin(p, b.Location, binSimilarityDistance) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func TestBinGridFindsTheSameBinAsALinearScan(t *testing.T) {
+	raw := loadTestCalibration(t)
+	d := newCalibrationData(raw, binSimilarityDistance, binHitThreshold)
+	d.aggregate()
+
+	points := 0
+	for _, r := range raw {
+		for _, l := range r.Locations {
+			if got, want := d.index.find(d.aggregated.Bins, l), linearFind(d.aggregated.Bins, l); got != want {
+				t.Fatalf("point %v is in bin %d, a linear scan finds bin %d", l, got, want)
+			}
+			points++
+		}
+	}
+
+	if points == 0 {
+		t.Fatal("the calibration doesn't have any points")
+	}
+}
+
+func TestBinGridAggregatesLikeALinearScan(t *testing.T) {
+	raw := loadTestCalibration(t)
+	d := newCalibrationData(raw, binSimilarityDistance, binHitThreshold)
+	d.aggregate()
+
+	// Aggregate again the way it was done before the grid
+	var bins []*Bin
+	for _, r := range raw {
+		for _, l := range r.Locations {
+			if i := linearFind(bins, l); i >= 0 {
+				bins[i].Hits++
+				for j := range r.Pixels {
+					bins[i].Pixels[j] += r.Pixels[j]
+				}
+				continue
+			}
+
+			pixels := make([]int32, len(r.Pixels))
+			copy(pixels, r.Pixels)
+			bins = append(bins, &Bin{Location: l, Pixels: pixels, Hits: 1})
+		}
+	}
+
+	if len(bins) != len(d.aggregated.Bins) {
+		t.Fatalf("got %d bins, a linear scan makes %d", len(d.aggregated.Bins), len(bins))
+	}
+
+	for i, b := range bins {
+		got := d.aggregated.Bins[i]
+		if got.Location != b.Location || got.Hits != b.Hits {
+			t.Fatalf("bin %d is at %v with %d hits, a linear scan has %v with %d", i, got.Location, got.Hits,
+				b.Location, b.Hits)
+		}
+
+		for j := range b.Pixels {
+			if got.Pixels[j] != b.Pixels[j] {
+				t.Fatalf("bin %d has %d for pixel %d, a linear scan has %d", i, got.Pixels[j], j, b.Pixels[j])
+			}
+		}
+	}
+}
+
+func BenchmarkAggregate(b *testing.B) {
+	raw := loadTestCalibration(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d := newCalibrationData(raw, binSimilarityDistance, binHitThreshold)
+		d.aggregate()
+	}
+}
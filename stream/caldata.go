@@ -1,5 +1,14 @@
 package stream
 
+// CalibrationDir is where calibration stores what it captured and the results.
+const CalibrationDir = "caldata"
+
+// ResolvedPixelsPath is where calibration stores the location of each pixel.
+const ResolvedPixelsPath = CalibrationDir + "/resolved.json"
+
+// InterpolatedPixelsPath is where calibration stores the location of each pixel after the gaps are filled.
+const InterpolatedPixelsPath = CalibrationDir + "/interpolated.json"
+
 // Point that represents LED location
 type Point struct {
 	X float64 `json:"x"`
@@ -24,6 +33,7 @@ type AckMessage struct {
 }
 
 type RawCalibrationData struct {
+	Frame     int     `json:"frame"`
 	Pixels    []int32 `json:"pixels"`
 	Locations []Point `json:"locations"`
 }
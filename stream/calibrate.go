@@ -6,8 +6,8 @@ import (
 	"log"
 	"math"
 	"os"
+	"path/filepath"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
@@ -24,6 +24,8 @@ const (
 type Calibrate struct {
 	config         Config
 	client         mqtt.Client
+	layout         *Layout
+	strategy       calibrationStrategy
 	C              chan bool
 	started        bool
 	iteration      int
@@ -35,29 +37,36 @@ type Calibrate struct {
 	ackChan        chan AckMessage
 	dataChan       chan DataMessage
 	rawData        []*RawCalibrationData
-	aggregated     *AggregatedData
+	stop           chan struct{}
 
-	binWriteLock sync.Mutex
+	startLock sync.Mutex
 }
 
 // NewCalibrate creates an instance of a Calibrate struct
-func NewCalibrate(config Config, client mqtt.Client) *Calibrate {
+func NewCalibrate(config Config, client mqtt.Client, layout *Layout) (*Calibrate, error) {
+	strategy, err := newCalibrationStrategy(config.Calibration.Strategy)
+	if err != nil {
+		return nil, err
+	}
+
 	c := new(Calibrate)
 	c.config = config
+	c.strategy = strategy
 	c.client = client
+	c.layout = layout
 	c.C = make(chan bool)
 	c.ackChan = make(chan AckMessage, 50)
 	c.dataChan = make(chan DataMessage, 50)
 	c.started = false
 	c.ackID = 0
 
-	c.onscreenFrame = NewFrame()
-	c.offscreenFrame = NewFrame()
+	c.onscreenFrame = NewFrame(c.layout)
+	c.offscreenFrame = NewFrame(c.layout)
 
 	// Turn all the lights on for the initial animation state
-	c.showCalibrationFrame(1, 0, false)
+	c.showCalibrationFrame(intervalLit(c.offscreenFrame.Len(), 1, 0), false)
 
-	return c
+	return c, nil
 }
 
 func (c *Calibrate) incrementAckID() uint8 {
@@ -72,32 +81,26 @@ func (c *Calibrate) incrementAckID() uint8 {
 }
 
 func (c *Calibrate) prepareFS() {
-	os.RemoveAll("caldata")
-	os.MkdirAll("caldata/raw", 0755)
-	os.MkdirAll("caldata/pixels", 0755)
+	os.RemoveAll(CalibrationDir)
+	os.MkdirAll(filepath.Join(CalibrationDir, "raw"), 0755)
+	os.MkdirAll(filepath.Join(CalibrationDir, "pixels"), 0755)
 }
 
-func (c *Calibrate) showCalibrationFrame(interval int, offset int, ack bool) []int32 {
-	pixelCount := len(c.offscreenFrame.pixels)
-	lit := make([]int32, pixelCount, pixelCount)
-
+func (c *Calibrate) showCalibrationFrame(lit []int32, ack bool) {
+	pixelCount := c.offscreenFrame.Len()
 	for i := 0; i < pixelCount; i++ {
-		if (i-offset)%interval == 0 {
+		if lit[i] > 0 {
 			c.offscreenFrame.pixels[i], _ = colorful.Hex("#202020")
-			lit[i] = 1
 		} else {
 			c.offscreenFrame.pixels[i], _ = colorful.Hex("#000000")
-			lit[i] = 0
 		}
 	}
 
 	c.actionFrame(true, ack)
-
-	return lit
 }
 
 func (c *Calibrate) showStatusFrame(resolved []Pixel) {
-	pixelCount := len(c.offscreenFrame.pixels)
+	pixelCount := c.offscreenFrame.Len()
 	for i := 0; i < pixelCount; i++ {
 		if resolved[i].Resolved {
 			c.offscreenFrame.pixels[i], _ = colorful.Hex("#002000")
@@ -141,52 +144,91 @@ func (c *Calibrate) handleCalClientMessages(client mqtt.Client, msg mqtt.Message
 		return
 	}
 
-	if !c.started && message.Type == "start" {
-		go c.runCalibration()
-	} else if c.started && message.Type == "data" {
+	if message.Type == "start" {
+		if err := c.Start(); err != nil {
+			log.Printf("Ignoring calibration start. %s", err)
+		}
+	} else if c.Calibrating() && message.Type == "data" {
 		var dataMsg DataMessage
 		json.Unmarshal(msg.Payload(), &dataMsg)
 		c.dataChan <- dataMsg
 	}
 }
 
-func (c *Calibrate) handleAckMessages(client mqtt.Client, msg mqtt.Message) {
-	var message AckMessage
-	if err := json.Unmarshal(msg.Payload(), &message); err != nil {
-		log.Printf("Failed to decode ACK message. %s", err)
-		return
+// Start begins a calibration in the background.
+func (c *Calibrate) Start() error {
+	c.startLock.Lock()
+	defer c.startLock.Unlock()
+
+	if c.started {
+		return fmt.Errorf("calibration is already running")
 	}
 
-	if message.Type == "ack" {
-		log.Printf("Recieved ACK %d, routing to channel.", message.AckID)
-		c.ackChan <- message
-	} else {
-		log.Printf("Unrecognised message type %s on ack queue.", message.Type)
+	c.started = true
+	c.stop = make(chan struct{})
+	go c.runCalibration(c.stop)
+	return nil
+}
+
+// Stop abandons a running calibration, or clears the status frame of a finished one, and returns to the
+// animations.
+func (c *Calibrate) Stop() error {
+	c.startLock.Lock()
+	defer c.startLock.Unlock()
+
+	if !c.started {
+		return fmt.Errorf("calibration isn't running")
 	}
+
+	close(c.stop)
+	c.started = false
+
+	// Tell the controller to stop the calibration
+	c.C <- false
+	return nil
 }
 
-func (c *Calibrate) resolve(aggregated *AggregatedData, resolved []Pixel) {
-	for _, bin := range aggregated.Bins {
-		var maxPixelFrequency int32 = 0 // Start with zero, ignore negative counts
-		pixel := -1
-		unique := true
-		for i, p := range bin.Pixels {
-			if p > maxPixelFrequency {
-				maxPixelFrequency = p
-				pixel = i
-				unique = true
-			} else if p == maxPixelFrequency {
-				unique = false
-			}
-		}
+// Calibrating reports whether a calibration has been started and not stopped.
+func (c *Calibrate) Calibrating() bool {
+	c.startLock.Lock()
+	defer c.startLock.Unlock()
+	return c.started
+}
 
-		if pixel > -1 && maxPixelFrequency > 0 && unique {
-			if !resolved[pixel].Resolved {
-				resolved[pixel].Location = bin.Location
-				resolved[pixel].Resolved = true
-			}
-		}
+// giveUp ends a calibration that can't carry on, unless it has already been stopped.
+func (c *Calibrate) giveUp(stop chan struct{}) {
+	c.startLock.Lock()
+	defer c.startLock.Unlock()
+
+	select {
+	case <-stop:
+		return
+	default:
+	}
+
+	c.started = false
+	c.C <- false
+}
+
+// ready tells the controller to show the calibration frames if the calibration hasn't been stopped.
+func (c *Calibrate) ready(stop chan struct{}) bool {
+	c.startLock.Lock()
+	defer c.startLock.Unlock()
+
+	select {
+	case <-stop:
+		return false
+	default:
 	}
+
+	c.C <- true
+	return true
+}
+
+// HandleAck routes an ACK from the device to the running calibration.
+func (c *Calibrate) HandleAck(ackID uint8) {
+	log.Printf("Recieved ACK %d, routing to channel.", ackID)
+	c.ackChan <- AckMessage{CalibrationMessage: CalibrationMessage{Type: "ack"}, AckID: ackID}
 }
 
 func (c *Calibrate) drainAckChannel() {
@@ -211,108 +253,100 @@ func (c *Calibrate) drainDataChannel() {
 	}
 }
 
-func (c *Calibrate) runCalibration() {
-	c.started = true
-	pixelCount := len(c.offscreenFrame.pixels)
-	c.aggregated = &AggregatedData{Bins: make([]*Bin, 0, 5000)}
+func (c *Calibrate) runCalibration(stop chan struct{}) {
+	pixelCount := c.offscreenFrame.Len()
 	c.ackID = 0
-	intervals := []int{1, 2, 3, 5, 7, 11} //, 13, 17, 19}
-	rawDataCount := 0
-	for _, interval := range intervals {
-		rawDataCount += interval
-	}
-
-	c.rawData = make([]*RawCalibrationData, 0, rawDataCount)
+	frames := c.strategy.frames(pixelCount)
+	c.rawData = make([]*RawCalibrationData, 0, len(frames))
 
 	// Allow the camera to adjust exposure
-	c.showCalibrationFrame(1, 0, false)
-	time.Sleep(2 * time.Second)
+	c.showCalibrationFrame(intervalLit(pixelCount, 1, 0), false)
+	select {
+	case <-time.After(2 * time.Second):
+	case <-stop:
+		log.Println("Calibration stopped")
+		return
+	}
 
-	// Tell the controller that we're ready to start showing frames
-	c.C <- true
+	// Tell the controller that we're ready to start showing frames, unless we were stopped while waiting
+	if !c.ready(stop) {
+		log.Println("Calibration stopped")
+		return
+	}
 
 	c.prepareFS()
 	capture := 0
 	var importWaitGroup sync.WaitGroup
 
-	for _, interval := range intervals {
-		for o := 0; o < interval; o++ {
-			// Make sure there are no ACKs in the channel
-			c.drainAckChannel()
-
-			// Show the frame
-			lit := c.showCalibrationFrame(interval, o, true)
-			currentAckID := c.onscreenFrame.ackID
-
-			// Loop until we get an ACK
-			exitTimeout := time.NewTimer(30 * time.Second)
-			gotAck := false
-			for !gotAck {
-				ackTimeout := time.NewTimer(1000 * time.Millisecond)
-				select {
-				case msg := <-c.ackChan:
-					if currentAckID == msg.AckID {
-						gotAck = true
-					} else {
-						log.Printf("Frame ACK %d (miss)", msg.AckID)
-					}
-				case <-ackTimeout.C:
-					log.Printf("Timed-out waiting for ACK %d, incrementing the ackID", currentAckID)
-					c.actionFrame(false, true)
-					currentAckID = c.onscreenFrame.ackID
-				case <-exitTimeout.C:
-					log.Println("Can't get an ACK from ledrx, giving up the calibration")
-					// Tell the controller to stop the calibration
-					c.C <- false
-					return
+	for f, frame := range frames {
+		// Make sure there are no ACKs in the channel
+		c.drainAckChannel()
+
+		// Show the frame
+		c.showCalibrationFrame(frame.lit, true)
+		currentAckID := c.onscreenFrame.ackID
+
+		// Loop until we get an ACK
+		exitTimeout := time.NewTimer(30 * time.Second)
+		gotAck := false
+		for !gotAck {
+			ackTimeout := time.NewTimer(1000 * time.Millisecond)
+			select {
+			case msg := <-c.ackChan:
+				if currentAckID == msg.AckID {
+					gotAck = true
+				} else {
+					log.Printf("Frame ACK %d (miss)", msg.AckID)
 				}
+			case <-ackTimeout.C:
+				log.Printf("Timed-out waiting for ACK %d, incrementing the ackID", currentAckID)
+				c.actionFrame(false, true)
+				currentAckID = c.onscreenFrame.ackID
+			case <-exitTimeout.C:
+				log.Println("Can't get an ACK from ledrx, giving up the calibration")
+				c.giveUp(stop)
+				return
+			case <-stop:
+				log.Println("Calibration stopped")
+				return
 			}
+		}
 
-			// Grab a snapshot for the frame that's been shown (each snapshot takes multiple pictures in the app)
-			gotData := false
-			for !gotData {
-				c.drainDataChannel()
-				token := c.client.Publish(c.config.Mqtt.Topics.CalibrateServer, 0, false, "snapshot")
-				token.Wait()
-
-				t := time.NewTimer(5 * time.Second)
-				select {
-				case msg := <-c.dataChan:
-					importWaitGroup.Add(1)
-					go c.importCalibrationMessage(msg, lit, capture, interval, o, &importWaitGroup)
-					//time.Sleep(40 * time.Millisecond)
-					gotData = true
-				case <-t.C:
-					log.Println("Data message timed-out, retrying...")
-					time.Sleep(1 * time.Second) // Back-off a little
-				}
-
-				capture++
+		// Grab a snapshot for the frame that's been shown (each snapshot takes multiple pictures in the app)
+		gotData := false
+		for !gotData {
+			c.drainDataChannel()
+			token := c.client.Publish(c.config.Mqtt.Topics.CalibrateServer, 0, false, "snapshot")
+			token.Wait()
+
+			t := time.NewTimer(5 * time.Second)
+			select {
+			case msg := <-c.dataChan:
+				importWaitGroup.Add(1)
+				go c.importCalibrationMessage(msg, f, frame, capture, &importWaitGroup)
+				//time.Sleep(40 * time.Millisecond)
+				gotData = true
+			case <-t.C:
+				log.Println("Data message timed-out, retrying...")
+				time.Sleep(1 * time.Second) // Back-off a little
+			case <-stop:
+				log.Println("Calibration stopped")
+				return
 			}
+
+			capture++
 		}
 	}
 
 	importWaitGroup.Wait()
 	log.Println("########## DONE CAPTURING")
 
-	c.aggregate()
-	log.Printf("Bin count (total): %d", len(c.aggregated.Bins))
-
-	highHitData := &AggregatedData{Bins: make([]*Bin, 0, 10000)}
-	for _, b := range c.aggregated.Bins {
-		if b.Hits >= binHitThreshold {
-			highHitData.Bins = append(highHitData.Bins, b)
-		}
+	resolved, summary, err := resolveCalibration(c.rawData, pixelCount, DefaultCalibrationOptions(c.config), CalibrationDir)
+	if err != nil {
+		log.Printf("Failed to resolve the calibration. %s", err)
+		return
 	}
-	log.Printf("Bin count (hits): %d", len(highHitData.Bins))
-
-	c.store(c.aggregated, "caldata/aggregated_raw.json")
-	c.store(highHitData, "caldata/aggregated.json")
-
-	resolved := make([]Pixel, pixelCount, pixelCount)
-	c.resolve(c.aggregated, resolved)
-	c.store(resolved, "caldata/resolved.json")
-	log.Println("Resolved")
+	log.Printf("Calibration %s", summary)
 
 	c.showStatusFrame(resolved)
 
@@ -321,70 +355,14 @@ func (c *Calibrate) runCalibration() {
 	log.Println("Published resolved")
 }
 
-func (c *Calibrate) aggregate() {
-	// var wg sync.WaitGroup
-	for _, r := range c.rawData {
-		for _, l := range r.Locations {
-			//wg.Add(len(r.Locations))
-			c.incrementBin(l, r.Pixels)
-		}
-	}
-
-	//wg.Wait()
-}
-
-func (c *Calibrate) doIncrementBin(bin *Bin, lit []int32) {
-	atomic.AddInt32(&bin.Hits, 1)
-	for j := 0; j < len(lit); j++ {
-		atomic.AddInt32(&bin.Pixels[j], lit[j])
-	}
-}
-
-func (c *Calibrate) incrementBin(binLocation Point, lit []int32) {
-	found := false
-	for i := 0; i < len(c.aggregated.Bins); i++ {
-		if isBin(binLocation, c.aggregated.Bins[i].Location, binSimilarityDistance) {
-			c.doIncrementBin(c.aggregated.Bins[i], lit)
-			found = true
-			break
-		}
-	}
-
-	if !found {
-		c.binWriteLock.Lock()
-		// Search again, another goroutine may have beaten us to it
-		found = false
-		var foundBin *Bin
-		for i := 0; i < len(c.aggregated.Bins); i++ {
-			if isBin(binLocation, c.aggregated.Bins[i].Location, binSimilarityDistance) {
-				found = true
-				foundBin = c.aggregated.Bins[i]
-				break
-			}
-		}
-
-		// If the bin is still not present while we're locked, add it
-		if !found {
-			litCopy := make([]int32, len(lit))
-			copy(litCopy, lit)
-			c.aggregated.Bins = append(c.aggregated.Bins, &Bin{Location: binLocation, Pixels: litCopy, Hits: 1})
-		}
-		c.binWriteLock.Unlock()
-
-		// If we found it then another goroutine added it, so just increment as usual
-		if found {
-			c.doIncrementBin(foundBin, lit)
-		}
-	}
-}
-
 func isBin(a Point, b Point, threshold float64) bool {
 	return math.Sqrt(math.Pow(math.Abs(a.X-b.X), 2.0)+math.Pow(math.Abs(a.Y-b.Y), 2.0)) < threshold
 }
 
-func (c *Calibrate) convertCalibrationMessage(locations []float64, lit []int32) *RawCalibrationData {
+func (c *Calibrate) convertCalibrationMessage(locations []float64, f int, lit []int32) *RawCalibrationData {
 	pointCount := len(locations) / 2
 	r := &RawCalibrationData{
+		Frame:     f,
 		Pixels:    lit,
 		Locations: make([]Point, pointCount, pointCount),
 	}
@@ -400,31 +378,18 @@ func (c *Calibrate) convertCalibrationMessage(locations []float64, lit []int32)
 }
 
 func (c *Calibrate) store(data interface{}, filePath string) {
-	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY, 0664)
-	if err != nil {
-		panic(err.Error)
-	}
-
-	serialised, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		f.Close()
-		panic(err.Error)
-	}
-	_, err = f.Write(serialised)
-	if err != nil {
-		f.Close()
-		panic(err.Error)
+	if err := writeCalibrationFile(data, filePath); err != nil {
+		panic(err.Error())
 	}
-	f.Close()
 }
 
-func (c *Calibrate) importCalibrationMessage(msg DataMessage, lit []int32, capture int, interval int,
-	offset int, wg *sync.WaitGroup) {
+func (c *Calibrate) importCalibrationMessage(msg DataMessage, f int, frame calibrationFrame, capture int,
+	wg *sync.WaitGroup) {
 
 	for iteration, l := range msg.Locations {
-		r := c.convertCalibrationMessage(l, lit)
+		r := c.convertCalibrationMessage(l, f, frame.lit)
 		c.rawData = append(c.rawData, r)
-		c.store(r, fmt.Sprintf("caldata/raw/raw-%03d-%02d-%02d-%02d.json", capture, iteration, interval, offset))
+		c.store(r, filepath.Join(CalibrationDir, "raw", fmt.Sprintf("raw-%03d-%02d-%s.json", capture, iteration, frame.label)))
 	}
 	wg.Done()
 }
@@ -443,9 +408,4 @@ func (c *Calibrate) Subscribe() {
 		log.Println(token.Error())
 		os.Exit(1)
 	}
-
-	if token := c.client.Subscribe(c.config.Mqtt.Topics.Ack, 0, c.handleAckMessages); token.Wait() && token.Error() != nil {
-		log.Println(token.Error())
-		os.Exit(1)
-	}
 }
@@ -0,0 +1,221 @@
+package stream
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+const defaultCalibrationStrategy = "primes"
+
+// calibrationFrame is a pattern of lit pixels that's shown to the camera during calibration.
+type calibrationFrame struct {
+	label string // Identifies the frame in the names of the raw data files
+	lit   []int32
+}
+
+// A calibrationStrategy decides which pixels are lit in each calibration frame and works out which pixel is
+// in each bin from what the camera saw. Resolving also gets the pixels that weren't resolved because another
+// pixel was just as likely to be in a bin.
+type calibrationStrategy interface {
+	frames(pixelCount int) []calibrationFrame
+	resolve(data *calibrationData, resolved []Pixel) (ambiguous []bool)
+}
+
+func newCalibrationStrategy(name string) (calibrationStrategy, error) {
+	switch name {
+	case "", "primes":
+		return primeStrategy{intervals: []int{1, 2, 3, 5, 7, 11}}, nil
+	case "gray":
+		return grayCodeStrategy{}, nil
+	default:
+		return nil, fmt.Errorf("calibration strategy should be primes or gray, not %q", name)
+	}
+}
+
+// primeStrategy lights every nth pixel for each offset of some prime intervals. The pixel in a bin is the
+// one that was lit most often when the bin was seen.
+type primeStrategy struct {
+	intervals []int
+}
+
+func intervalLit(pixelCount int, interval int, offset int) []int32 {
+	lit := make([]int32, pixelCount, pixelCount)
+	for i := 0; i < pixelCount; i++ {
+		if (i-offset)%interval == 0 {
+			lit[i] = 1
+		}
+	}
+
+	return lit
+}
+
+func (s primeStrategy) frames(pixelCount int) []calibrationFrame {
+	frames := make([]calibrationFrame, 0)
+	for _, interval := range s.intervals {
+		for o := 0; o < interval; o++ {
+			label := fmt.Sprintf("%02d-%02d", interval, o)
+			frames = append(frames, calibrationFrame{label: label, lit: intervalLit(pixelCount, interval, o)})
+		}
+	}
+
+	return frames
+}
+
+func (s primeStrategy) resolve(data *calibrationData, resolved []Pixel) []bool {
+	ambiguous := make([]bool, len(resolved))
+	for _, bin := range data.aggregated.Bins {
+		if bin.Hits < data.minHits {
+			continue
+		}
+
+		var maxPixelFrequency int32 = 0 // Start with zero, ignore negative counts
+		pixel := -1
+		unique := true
+		for i, p := range bin.Pixels {
+			if p > maxPixelFrequency {
+				maxPixelFrequency = p
+				pixel = i
+				unique = true
+			} else if p == maxPixelFrequency {
+				unique = false
+			}
+		}
+
+		if pixel > -1 && maxPixelFrequency > 0 && unique {
+			if !resolved[pixel].Resolved {
+				resolved[pixel].Location = bin.Location
+				resolved[pixel].Resolved = true
+			}
+		} else if pixel > -1 && maxPixelFrequency > 0 {
+			for i, p := range bin.Pixels {
+				if p == maxPixelFrequency {
+					ambiguous[i] = true
+				}
+			}
+		}
+	}
+
+	return ambiguous
+}
+
+// grayCodeStrategy gives each pixel a code from its index in Gray code, there's a frame for each bit and the
+// pixel is lit when its bit is set. A parity frame makes every code have an even number of set bits, so a bin
+// that was missed or seen by mistake in one frame is rejected instead of being given to the wrong pixel. Codes
+// start at 1 so that every pixel is lit at least once. 600 pixels take 11 frames.
+type grayCodeStrategy struct{}
+
+func grayCode(i int) uint {
+	return uint(i) ^ (uint(i) >> 1)
+}
+
+func fromGrayCode(code uint) int {
+	i := code
+	for shift := code >> 1; shift != 0; shift >>= 1 {
+		i ^= shift
+	}
+
+	return int(i)
+}
+
+func grayCodeBits(pixelCount int) int {
+	return bits.Len(uint(pixelCount))
+}
+
+func (s grayCodeStrategy) frames(pixelCount int) []calibrationFrame {
+	codeBits := grayCodeBits(pixelCount)
+	frames := make([]calibrationFrame, codeBits+1)
+	for b := range frames {
+		label := fmt.Sprintf("gray-%02d", b)
+		if b == codeBits {
+			label = "gray-parity"
+		}
+
+		lit := make([]int32, pixelCount, pixelCount)
+		for i := range lit {
+			code := grayCode(i + 1)
+			if b == codeBits {
+				lit[i] = int32(bits.OnesCount(code) % 2)
+			} else {
+				lit[i] = int32((code >> uint(b)) & 1)
+			}
+		}
+		frames[b] = calibrationFrame{label: label, lit: lit}
+	}
+
+	return frames
+}
+
+// resolve decodes the frames that each bin was seen in. A bin is on in a frame if it was seen in at least half
+// of the pictures of it, and a pixel that decodes from more than one bin goes to the bin with the most hits.
+// When a code fails the parity check, the pixels one bit away from it are ambiguous.
+func (s grayCodeStrategy) resolve(data *calibrationData, resolved []Pixel) []bool {
+	ambiguous := make([]bool, len(resolved))
+	codeBits := grayCodeBits(len(resolved))
+	pictures := make([]int, codeBits+1)
+	seen := make([][]int, len(data.aggregated.Bins))
+	for _, r := range data.raw {
+		if r.Frame < 0 || r.Frame > codeBits {
+			continue
+		}
+
+		pictures[r.Frame]++
+		for _, l := range r.Locations {
+			b := data.index.find(data.aggregated.Bins, l)
+			if b < 0 {
+				continue
+			}
+
+			if seen[b] == nil {
+				seen[b] = make([]int, codeBits+1)
+			}
+			seen[b][r.Frame]++
+		}
+	}
+
+	hits := make([]int32, len(resolved))
+	for b, frames := range seen {
+		bin := data.aggregated.Bins[b]
+		if frames == nil || bin.Hits < data.minHits {
+			continue
+		}
+
+		var code uint
+		setBits := 0
+		for f, count := range frames {
+			if count > 0 && count*2 >= pictures[f] {
+				setBits++
+				if f < codeBits {
+					code |= 1 << uint(f)
+				}
+			}
+		}
+
+		if setBits%2 != 0 {
+			// Either the parity frame was wrong or one of the code frames was
+			candidates := []uint{code}
+			for f := 0; f < codeBits; f++ {
+				candidates = append(candidates, code^(1<<uint(f)))
+			}
+
+			for _, candidate := range candidates {
+				if pixel := fromGrayCode(candidate) - 1; pixel >= 0 && pixel < len(resolved) {
+					ambiguous[pixel] = true
+				}
+			}
+			continue
+		}
+
+		pixel := fromGrayCode(code) - 1
+		if pixel < 0 || pixel >= len(resolved) {
+			continue
+		}
+
+		if !resolved[pixel].Resolved || bin.Hits > hits[pixel] {
+			resolved[pixel].Location = bin.Location
+			resolved[pixel].Resolved = true
+			hits[pixel] = bin.Hits
+		}
+	}
+
+	return ambiguous
+}
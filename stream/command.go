@@ -0,0 +1,143 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// CommandMessage is a request to change what the Controller is showing, received on the command topic.
+type CommandMessage struct {
+	Command string  `json:"command"`
+	Name    string  `json:"name,omitempty"`  // The animation for play or the favourite for save and recall
+	Value   float64 `json:"value,omitempty"` // The level for brightness
+	Mode    string  `json:"mode,omitempty"`  // The mode for saturation
+}
+
+// Commander runs commands from the command topic and publishes the Controller's status on the state topic.
+// It also looks after the Home Assistant light when that's enabled.
+type Commander struct {
+	config        Config
+	client        mqtt.Client
+	controller    *Controller
+	favourites    *Favourites
+	homeAssistant *HomeAssistant
+}
+
+// NewCommander creates an instance of a Commander.
+func NewCommander(config Config, client mqtt.Client, controller *Controller, favourites *Favourites) *Commander {
+	c := new(Commander)
+	c.config = config
+	c.client = client
+	c.controller = controller
+	c.favourites = favourites
+	if config.HomeAssistant.Enabled {
+		c.homeAssistant = NewHomeAssistant(config, client, controller)
+	}
+
+	return c
+}
+
+func (c *Commander) run(message CommandMessage) error {
+	switch message.Command {
+	case "next":
+		return c.controller.Next()
+	case "previous":
+		return c.controller.Previous()
+	case "play":
+		return c.controller.Play(message.Name, nil)
+	case "brightness":
+		return c.controller.SetBrightness(message.Value)
+	case "saturation":
+		return c.controller.SetSaturationMode(message.Mode)
+	case "pause":
+		return c.controller.SetCycling(false)
+	case "resume":
+		return c.controller.SetCycling(true)
+	case "save":
+		_, err := c.favourites.Save(message.Name, c.controller.Status().Current)
+		return err
+	case "recall":
+		favourite, ok := c.favourites.Get(message.Name)
+		if !ok {
+			return fmt.Errorf("unknown favourite %q", message.Name)
+		}
+		return c.controller.PlayFavourite(message.Name, favourite)
+	case "off":
+		c.controller.SetPower(false)
+	case "on":
+		c.controller.SetPower(true)
+	default:
+		return fmt.Errorf("unrecognised command %q", message.Command)
+	}
+
+	return nil
+}
+
+func (c *Commander) handleCommandMessages(client mqtt.Client, msg mqtt.Message) {
+	var message CommandMessage
+	if err := json.Unmarshal(msg.Payload(), &message); err != nil {
+		log.Printf("Failed to decode command message. %s", err)
+		return
+	}
+
+	if err := c.run(message); err != nil {
+		log.Printf("Failed to run command %s. %s", message.Command, err)
+	}
+}
+
+// PublishState publishes the Controller's status as a retained message.
+func (c *Commander) PublishState() {
+	if c.config.Mqtt.Topics.State == "" {
+		return
+	}
+
+	b, err := json.Marshal(c.controller.Status())
+	if err != nil {
+		log.Printf("Failed to encode state. %s", err)
+		return
+	}
+
+	token := c.client.Publish(c.config.Mqtt.Topics.State, 0, true, b)
+	token.Wait()
+	if token.Error() != nil {
+		log.Printf("Failed to publish state. %s", token.Error())
+	}
+}
+
+// Run publishes the state every time that it changes.
+func (c *Commander) Run() {
+	for range c.controller.StateChanged() {
+		if c.client.IsConnected() {
+			c.PublishState()
+			if c.homeAssistant != nil {
+				c.homeAssistant.PublishState()
+			}
+		}
+	}
+}
+
+// Subscribe to listen for commands, the availability and current state are published too because they're
+// lost on reconnection if the broker doesn't persist them.
+func (c *Commander) Subscribe() {
+	if c.config.Mqtt.Topics.Availability != "" {
+		token := c.client.Publish(c.config.Mqtt.Topics.Availability, 0, true, AvailabilityOnline)
+		if token.Wait() && token.Error() != nil {
+			log.Printf("Failed to publish availability. %s", token.Error())
+		}
+	}
+
+	if c.homeAssistant != nil {
+		c.homeAssistant.Subscribe()
+	}
+
+	if c.config.Mqtt.Topics.Command != "" {
+		if token := c.client.Subscribe(c.config.Mqtt.Topics.Command, 0, c.handleCommandMessages); token.Wait() && token.Error() != nil {
+			log.Printf("Failed to subscribe to commands. %s", token.Error())
+		}
+	}
+
+	c.PublishState()
+}
@@ -1,5 +1,7 @@
 package stream
 
+import "time"
+
 // Config for the application
 type Config struct {
 	Mqtt struct {
@@ -11,6 +13,76 @@ type Config struct {
 			Ack             string `yaml:"ack"`
 			CalibrateClient string `yaml:"calibrateClient"`
 			CalibrateServer string `yaml:"calibrateServer"`
+			Command         string `yaml:"command"`
+			State           string `yaml:"state"`
+			Availability    string `yaml:"availability"`
 		}
 	} `yaml:"mqtt"`
+	Layout    Layout `yaml:"layout"`
+	Transport struct {
+		Type string `yaml:"type"`
+		Udp  struct {
+			Address string `yaml:"address"`
+		} `yaml:"udp"`
+		File struct {
+			Path string `yaml:"path"`
+		} `yaml:"file"`
+		Ddp struct {
+			Address string `yaml:"address"`
+		} `yaml:"ddp"`
+		Dmx struct {
+			Address   string            `yaml:"address"`
+			Source    string            `yaml:"source"`
+			Priority  int               `yaml:"priority"`
+			Universes []UniverseMapping `yaml:"universes"`
+		} `yaml:"dmx"`
+	} `yaml:"transport"`
+	Output struct {
+		Gamma   float64 `yaml:"gamma"`
+		Balance struct {
+			Red   *float64 `yaml:"red"`
+			Green *float64 `yaml:"green"`
+			Blue  *float64 `yaml:"blue"`
+		} `yaml:"balance"`
+		Dither bool `yaml:"dither"`
+	} `yaml:"output"`
+	Power       PowerConfig         `yaml:"power"`
+	FrameRate   float64             `yaml:"frameRate"`
+	Playlist    string              `yaml:"playlist"`
+	Playlists   map[string]Playlist `yaml:"playlists"`
+	Saturation  string              `yaml:"saturation"`
+	Seed        *int64              `yaml:"seed"` // Makes the show repeatable, it's chosen at random if it's not set
+	Favourites  string              `yaml:"favourites"`
+	Schedule    ScheduleConfig      `yaml:"schedule"`
+	Calibration struct {
+		Strategy string `yaml:"strategy"` // primes (the default) or gray
+	} `yaml:"calibration"`
+
+	HomeAssistant HomeAssistantConfig `yaml:"homeAssistant"`
+}
+
+// PowerConfig describes the power supply and how much current each LED draws.
+type PowerConfig struct {
+	MilliampsPerChannel   float64       `yaml:"milliampsPerChannel"`
+	IdleMilliampsPerPixel float64       `yaml:"idleMilliampsPerPixel"`
+	SupplyMilliamps       float64       `yaml:"supplyMilliamps"`
+	HeadroomPercent       float64       `yaml:"headroomPercent"`
+	Volts                 float64       `yaml:"volts"`
+	ReportInterval        time.Duration `yaml:"reportInterval"`
+}
+
+// Headroom gets the fraction of the supply that frames are allowed to use.
+func (p PowerConfig) Headroom() float64 {
+	return 1.0 - (p.HeadroomPercent / 100.0)
+}
+
+// HomeAssistantConfig describes how ledtx appears as a light in Home Assistant. The command and state topics
+// default to ones under the discovery prefix.
+type HomeAssistantConfig struct {
+	Enabled         bool   `yaml:"enabled"`
+	DiscoveryPrefix string `yaml:"discoveryPrefix"`
+	ObjectID        string `yaml:"objectId"`
+	Name            string `yaml:"name"`
+	Command         string `yaml:"command"`
+	State           string `yaml:"state"`
 }
@@ -2,13 +2,13 @@ package stream
 
 import (
 	"fmt"
+	"hash/fnv"
 	"log"
+	"math"
 	"math/rand"
+	"sort"
+	"sync"
 	"time"
-
-	"github.com/lucasb-eyer/go-colorful"
-	"github.com/matt-g-everett/ledtx/stream/stripe"
-	"github.com/matt-g-everett/ledtx/util"
 )
 
 const (
@@ -20,155 +20,152 @@ const (
 	SaturationMaxWide  = 1.0
 )
 
-var SaturationMin = SaturationMinChill
-var SaturationMax = SaturationMaxChill
+const defaultSaturationMode = "chill"
+
+// A SaturationMode is the range of saturations that random colours are chosen from.
+type SaturationMode struct {
+	Min float64
+	Max float64
+}
+
+// saturationModes are the moods that can be chosen by name.
+var saturationModes = map[string]SaturationMode{
+	"chill": {SaturationMinChill, SaturationMaxChill},
+	"fun":   {SaturationMinFun, SaturationMaxFun},
+	"wide":  {SaturationMinWide, SaturationMaxWide},
+}
+
+// SaturationModes gets the names of the saturation modes sorted by name.
+func SaturationModes() []string {
+	names := make([]string, 0, len(saturationModes))
+	for name := range saturationModes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// AnimationInfo describes an animation that the Controller is showing.
+type AnimationInfo struct {
+	Name      string `json:"name"`
+	Animation string `json:"animation"`
+	Index     int    `json:"index"`
+	Params    Params `json:"params"`
+	Seed      int64  `json:"seed"`
+}
+
+// ScheduleState is what the Scheduler wants the Controller to do at the moment.
+type ScheduleState struct {
+	Window        string  `json:"window"` // Empty outside of the schedule's windows
+	On            bool    `json:"on"`
+	Silent        bool    `json:"silent"` // Stop sending frames rather than sending black ones while off
+	BrightnessCap float64 `json:"brightnessCap"`
+}
+
+// ControllerStatus is a snapshot of what the Controller is doing.
+type ControllerStatus struct {
+	Playlist    string         `json:"playlist"`
+	Playlists   []string       `json:"playlists"`
+	Cycling     bool           `json:"cycling"`
+	Calibrating bool           `json:"calibrating"`
+	Power       bool           `json:"power"`
+	Brightness  float64        `json:"brightness"`
+	Saturation  string         `json:"saturation"`
+	Schedule    ScheduleState  `json:"schedule"`
+	Current     AnimationInfo  `json:"current"`
+	Next        *AnimationInfo `json:"next"`
+}
 
 // Controller that manages animations.
 type Controller struct {
-	calibrate           *Calibrate
-	animationIndex      int
-	animationPlaylist   []string
-	animation           Animation
-	nextAnimation       Animation
-	animationTime       time.Duration
-	cycling             bool
-	runtimeMs           int64
-	frameRate           float64
-	transition          float64
-	transitionTimeSecs  float64
-	transitionIncrement float64
-	rainbowGradient     GradientTable
-	rainbowStepGradient GradientTable
-}
-
-// NewController creates an instance of a Controller.
-func NewController(runtimeMs int64, frameRate float64, animationTime time.Duration,
-	calibrate *Calibrate) *Controller {
+	layout             *Layout
+	pixelMap           *PixelMap
+	calibrate          *Calibrate
+	animationIndex     int
+	playlists          map[string]Playlist
+	playlistName       string
+	playlist           Playlist
+	entryChanged       chan struct{}
+	stateChanged       chan struct{}
+	animation          Animation
+	animationInfo      AnimationInfo
+	nextAnimation      Animation
+	nextAnimationInfo  AnimationInfo
+	cycling            bool
+	calibrating        bool
+	power              bool
+	brightness         float64
+	saturationMode     string
+	saturation         SaturationMode
+	schedule           ScheduleState
+	runtimeMs          int64
+	transition         *Transition
+	transitionProgress float64
+	transitionStartMs  int64
+	transitionTimeSecs float64
+	seed               int64
+	plays              int
+
+	lock sync.Mutex
+}
 
-	c := new(Controller)
+// NewController creates an instance of a Controller. The seed decides every random choice that the
+// animations make, so the same seed and playlist give the same show.
+func NewController(layout *Layout, runtimeMs int64, playlists map[string]Playlist, playlistName string,
+	seed int64, calibrate *Calibrate) (*Controller, error) {
 
-	c.rainbowGradient = GradientTable{
-		{0.0, 1.0, 0.0},
-		{6.0, 1.0, 0.04},   // Pink
-		{87.0, 1.0, 0.14},  // Red
-		{88.0, 1.0, 0.28},  // Orange
-		{98.0, 1.0, 0.42},  // Yellow
-		{180.0, 1.0, 0.56}, // Green
-		{190.0, 1.0, 0.70}, // Turquiose
-		{320.0, 1.0, 0.84}, // Blue
-		{328.0, 1.0, 0.91}, // Violet
-		{360.0, 1.0, 1.0},  // Pink wrap
-	}
-
-	c.rainbowStepGradient = GradientTable{
-		{80.0, 1.0, 0.0},    // Red
-		{80.0, 1.0, 0.167},  // Red
-		{86.0, 1.0, 0.167},  // Orange
-		{86.0, 1.0, 0.333},  // Orange
-		{95.0, 1.0, 0.333},  // Yellow
-		{95.0, 1.0, 0.5},    // Yellow
-		{160.0, 1.0, 0.5},   // Green
-		{160.0, 1.0, 0.666}, // Green
-		{280.0, 1.0, 0.666}, // Blue
-		{280.0, 1.0, 0.833}, // Blue
-		{328.0, 1.0, 0.833}, // Violet
-		{328.0, 1.0, 1.0},   // Violet
-	}
+	c := new(Controller)
 
+	c.layout = layout
+	c.pixelMap = loadCalibratedPixelMap(layout)
 	c.animation = nil
 	c.nextAnimation = nil
 	c.calibrate = calibrate
-	c.animationTime = animationTime
+	c.playlists = playlists
+	c.entryChanged = make(chan struct{}, 1)
+	c.stateChanged = make(chan struct{}, 1)
 	c.cycling = true
+	c.calibrating = false
+	c.power = true
+	c.brightness = 1.0
+	c.saturationMode = defaultSaturationMode
+	c.saturation = saturationModes[defaultSaturationMode]
+	c.schedule = ScheduleState{On: true, BrightnessCap: 1.0}
+	c.seed = seed
+	c.plays = 0
 
 	c.runtimeMs = runtimeMs
-	c.frameRate = frameRate
-	c.transition = 0.0
+	c.transition = nil
+	c.transitionProgress = 0.0
+	c.transitionStartMs = -1
 	c.transitionTimeSecs = 5.0
-	c.transitionIncrement = 1.0 / (c.frameRate * c.transitionTimeSecs)
-
-	c.animationPlaylist = []string{
-		"multi:monokai",
-		//"streak:random",
-		"istripe:70s",
-		"istripe:random",
-		"multi:monokai",
-		"stripes:random",
-		"gradient:purplegoldblue",
-		"multi:purplegoldblue",
-		"istripe:random",
-		"multi:random",
-		"stripes:candycane",
-		"istripe:70s",
-		"gradient:pinkorangewhite",
-		"multi:random3",
-		"stripes:random",
-		"multi:pinkblueturquoise",
-		"gradient:rainbowstep",
-		"multi:purplegoldblue",
-		"istripe:70s",
-		"rainbow:fixed",
-		"multi:monokai",
-		"stripes:candycane",
-		"twinkle:random",
-		"istripe:random",
-		"multi:redgreengold",
-		"twinkle:blue",
-		"stripes:random",
-		"multi:random",
-		"istripe:70s",
-		"multi:pinksilverblue",
-		"rainbow:random",
-		"multi:purplegoldblue",
-		"twinkle:random",
-		"istripe:random",
-		"multi:random3",
-		"stripes:candycane",
-		"multi:random2",
-		"istripe:70s",
-		"twinkle:pink",
-		"stripes:random",
-		"multi:redgreengold",
-		"multi:monokai",
-		"rainbow:normal",
-		"multi:random",
-		"istripe:random",
-		"multi:redwhiteblue",
-		"gradient:pinkorangewhite",
-		"twinkle:random",
-		"stripes:candycane",
-		"multi:redgreengold",
-		"istripe:70s",
-		"twinkle:gold",
-		"stripes:random",
-		"multi:random2",
-		"rainbow:random",
-		"multi:purplegoldblue",
-		"stripes:random",
-		"twinkle:random",
-		"istripe:70s",
-		"istripe:random",
-		"multi:random3",
-		"stripes:candycane",
-		"multi:random",
-		"multi:pinksilverblue",
-		"twinkle:silver",
-		"stripes:random",
-		"multi:redwhiteblue",
-		"istripe:70s",
-		"rainbow:random",
-		"multi:random3",
+
+	if err := c.SetPlaylist(playlistName); err != nil {
+		return nil, err
 	}
-	c.animationIndex = 0
-	c.animation, _ = c.getAnimation()
 
-	return c
+	return c, nil
 }
 
 // CalculateFrame calculates a frame using the current and next animation
 func (c *Controller) CalculateFrame(runtimeMs int64) *Frame {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	var f *Frame
 	c.runtimeMs = runtimeMs
+	if !c.schedule.On && !c.calibrating {
+		if c.schedule.Silent {
+			return nil
+		}
+		return NewFrame(c.layout)
+	}
+
+	if !c.power && !c.calibrating {
+		return NewFrame(c.layout)
+	}
+
 	if c.nextAnimation != nil {
 		f1 := c.animation.CalculateFrame(runtimeMs)
 		f2 := c.nextAnimation.CalculateFrame(runtimeMs)
@@ -176,288 +173,413 @@ func (c *Controller) CalculateFrame(runtimeMs int64) *Frame {
 			return f2
 		}
 
-		f = f1.InterpolateFrame(f2, c.transition)
-		c.transition += c.transitionIncrement
+		// The transition is timed from the first frame that it's rendered in
+		if c.transitionStartMs < 0 {
+			c.transitionStartMs = runtimeMs
+		}
+		c.transitionProgress = 1.0
+		if c.transitionTimeSecs > 0 {
+			c.transitionProgress = math.Min(float64(runtimeMs-c.transitionStartMs)/(c.transitionTimeSecs*1000.0), 1.0)
+		}
+
+		f = c.transition.Blend(f1, f2, c.transitionProgress)
 
-		if c.transition >= 1.0 {
+		if c.transitionProgress >= 1.0 {
 			c.animation = c.nextAnimation
+			c.animationInfo = c.nextAnimationInfo
 			c.nextAnimation = nil
-			c.transition = 0.0
+			c.transition = nil
+			c.transitionProgress = 0.0
+			c.transitionStartMs = -1
+			c.notifyStateChanged()
 		}
 	} else {
 		f = c.animation.CalculateFrame(runtimeMs)
 	}
 
+	brightness := math.Min(c.brightness, c.schedule.BrightnessCap)
+	if f != nil && brightness < 1.0 && !c.calibrating {
+		f = f.scale(brightness)
+	}
+
 	return f
 }
 
-func (c *Controller) getRandomSpeed(low float64, high float64) float64 {
-	speed := (rand.Float64() * (high - low)) + low
-	sign := rand.Float64() - 0.5
-	if sign > 0 {
-		return speed
-	} else {
-		return speed * -1.0
+func (c *Controller) animationContext(seed int64) *AnimationContext {
+	return &AnimationContext{
+		Layout:        c.layout,
+		RuntimeMs:     c.runtimeMs,
+		SaturationMin: c.saturation.Min,
+		SaturationMax: c.saturation.Max,
+		Rand:          rand.New(rand.NewSource(seed)),
+		PixelMap:      c.pixelMap,
 	}
 }
 
-func (c *Controller) createStripes(stripeColours []colorful.Color) GradientTable {
-	numStripes := len(stripeColours)
-	table := make(GradientTable, 0, numStripes)
-	increment := 1.0 / float64(numStripes)
-	for i := 0; i < numStripes; i++ {
-		h, c, _ := stripeColours[i].Hcl()
-		table = append(table, GradientTable{{h, c, float64(i) * increment}}...)
-		table = append(table, GradientTable{{h, c, float64(i+1) * increment}}...)
+// entrySeed derives the seed for an entry from the show's seed and the entry's position in the playlist.
+// Entries that aren't in the playlist are numbered by how many have been played.
+func (c *Controller) entrySeed(entry PlaylistEntry, index int) int64 {
+	if entry.Seed != nil {
+		return *entry.Seed
 	}
 
-	return table
+	h := fnv.New64a()
+	if index >= 0 {
+		fmt.Fprintf(h, "%d/%s/%d", c.seed, c.playlistName, index)
+	} else {
+		c.plays++
+		fmt.Fprintf(h, "%d/play/%s/%d", c.seed, entry.Name, c.plays)
+	}
+
+	return int64(h.Sum64())
 }
 
-func (c *Controller) createKnownTwinkle(foreColour colorful.Color, backColour colorful.Color) Animation {
-	return NewMultiTwinkle(rand.Int31n(40)+20, []colorful.Color{backColour}, nil, c.runtimeMs)
+// getAnimation creates the animation for an entry, index is its position in the playlist or -1. The info
+// includes the params and seed that it was created with.
+func (c *Controller) getAnimation(entry PlaylistEntry, index int) (Animation, AnimationInfo, error) {
+	seed := c.entrySeed(entry, index)
+	animation, params, err := NewAnimation(entry.Animation, entry.Params, c.animationContext(seed))
+	if err != nil {
+		return nil, AnimationInfo{}, err
+	}
+
+	log.Printf("Cycling to %s (seed %d); %s", entry.Name, seed, params.Describe())
+	info := AnimationInfo{Name: entry.Name, Animation: entry.Animation, Index: index, Params: params, Seed: seed}
+	return animation, info, nil
 }
 
-func (c *Controller) createRandomTwinkle(foreColour colorful.Color, saturationMin float64, saturationMax float64) (Animation, string) {
-	randomBackColour := colorful.Hsl(rand.Float64()*360.0, util.RandomiseSaturation(saturationMin, saturationMax), 0.02)
-	animation := NewMultiTwinkle(rand.Int31n(50)+20, []colorful.Color{randomBackColour}, nil, c.runtimeMs)
-	return animation, randomBackColour.Hex()
+func (c *Controller) currentEntry() PlaylistEntry {
+	return c.playlist[c.animationIndex]
 }
 
-func (c *Controller) createFixedRainbow() Animation {
-	return NewGradientTrail(c.rainbowGradient, 600, 0.06, c.runtimeMs, 0.0)
+func (c *Controller) cycleAnimation() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.cycling {
+		c.animationIndex++
+		c.animationIndex %= len(c.playlist)
+		c.startEntry()
+	}
 }
 
-func (c *Controller) createKnownRainbow() Animation {
-	return NewGradientTrail(c.rainbowGradient, 1200, 0.06, c.runtimeMs, -0.5)
+// startEntry transitions to the animation for the current playlist entry.
+func (c *Controller) startEntry() {
+	c.showEntry(c.currentEntry(), c.animationIndex)
 }
 
-func (c *Controller) createRandomRainbow() Animation {
-	trailLength := rand.Int31n(900) + 100
-	speedMin := float64(trailLength) * 0.0004
-	speedMax := float64(trailLength) * 0.0006
+// showEntry transitions to the animation for an entry, index is its position in the playlist or -1. Anything
+// that the entry doesn't set uses the defaults.
+func (c *Controller) showEntry(entry PlaylistEntry, index int) {
+	entry = entry.withDefaults()
+	animation, info, err := c.getAnimation(entry, index)
+	if err != nil {
+		log.Printf("Failed to create %s, staying on the current animation. %s", entry.Name, err)
+		return
+	}
+	defer c.notifyStateChanged()
+
+	if c.animation == nil {
+		// Nothing to transition from
+		c.animation = animation
+		c.animationInfo = info
+		return
+	}
 
-	saturation := util.RandomiseSaturation(0.2, 0.8)
-	adjustedGradient := make(GradientTable, len(c.rainbowGradient))
-	copy(adjustedGradient, c.rainbowGradient)
-	for i := 0; i < len(adjustedGradient); i++ {
-		adjustedGradient[i].Saturation = saturation
+	transitionContext := c.animationContext(info.Seed)
+	transition, err := NewTransition(entry.TransitionStyle, entry.Easing, transitionContext)
+	if err != nil {
+		log.Printf("Failed to create the transition to %s, using the default. %s", entry.Name, err)
+		transition, _ = NewTransition("", "", transitionContext)
 	}
 
-	return NewGradientTrail(adjustedGradient, uint32(trailLength), 0.06, c.runtimeMs, c.getRandomSpeed(speedMin, speedMax))
+	c.nextAnimation = animation
+	c.nextAnimationInfo = info
+	c.transition = transition
+	c.transitionProgress = 0.0
+	c.transitionStartMs = -1
+	c.transitionTimeSecs = entry.Transition.Seconds()
 }
 
-func (c *Controller) createGradient(gradient GradientTable, trailLength uint32, speed float64) Animation {
-	return NewGradientTrail(gradient, trailLength, 0.06, c.runtimeMs, speed)
+// notifyStateChanged lets whoever is watching StateChanged know that the status is different.
+func (c *Controller) notifyStateChanged() {
+	select {
+	case c.stateChanged <- struct{}{}:
+	default:
+	}
 }
 
-func (c *Controller) createGradientRandom(gradient GradientTable, trailLength uint32) Animation {
-	return NewGradientTrail(gradient, trailLength, 0.06, c.runtimeMs, c.getRandomSpeed(0.2, 0.5))
+// StateChanged signals after the Controller's status changes, changes that happen close together are merged.
+func (c *Controller) StateChanged() <-chan struct{} {
+	return c.stateChanged
 }
 
-func (c *Controller) createMultiTwinkle(backColours []colorful.Color) Animation {
-	return NewMultiTwinkle(rand.Int31n(50)+20, backColours, nil, c.runtimeMs)
+// notifyEntryChanged lets the timer know that the entry has changed so that it restarts.
+func (c *Controller) notifyEntryChanged() {
+	select {
+	case c.entryChanged <- struct{}{}:
+	default:
+	}
 }
 
-func (c *Controller) createRandomStripes(numColours int, saturationMin float64, saturationMax float64) (Animation, string) {
-	if numColours < 1 {
-		numColours = rand.Intn(4) + 2
+// jumpTo shows the entry at index in the current playlist.
+func (c *Controller) jumpTo(index int) error {
+	if c.calibrating {
+		return fmt.Errorf("calibration is running")
 	}
 
-	// 30% chance that one of the colours is white
-	whiteIndex := rand.Intn(numColours * 3)
-	trailLength := rand.Int31n(200) + 200
-	stripeColours := make([]colorful.Color, numColours)
-	for i := 0; i < numColours; i++ {
-		if i == whiteIndex {
-			stripeColours[i] = colorful.Hsl(0.0, 0.0, 0.4)
-		} else {
-			stripeColours[i] = colorful.Hsl(rand.Float64()*360.0, util.RandomiseSaturation(saturationMin, saturationMax), 0.5)
+	c.animationIndex = index
+	c.startEntry()
+	c.notifyEntryChanged()
+	return nil
+}
+
+// Next skips to the next entry in the playlist.
+func (c *Controller) Next() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.jumpTo((c.animationIndex + 1) % len(c.playlist))
+}
+
+// Previous goes back to the previous entry in the playlist.
+func (c *Controller) Previous() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.jumpTo((c.animationIndex + len(c.playlist) - 1) % len(c.playlist))
+}
+
+// Jump goes to the first entry in the playlist with a name.
+func (c *Controller) Jump(name string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for i, entry := range c.playlist {
+		if entry.Name == name {
+			return c.jumpTo(i)
 		}
 	}
-	extraInfo := "colours: "
-	extraInfo += c.SprintColours(stripeColours)
 
-	stripeTable := c.createStripes(stripeColours)
-	return NewGradientTrail(stripeTable, uint32(trailLength), 0.2, c.runtimeMs, c.getRandomSpeed(0.3, 0.4)), extraInfo
+	return fmt.Errorf("playlist %q doesn't have an entry called %q", c.playlistName, name)
+}
+
+// Play shows an entry from the playlist, or any animation type or preset, by name. Without params it jumps to
+// the playlist entry with that name, otherwise the animation is shown with params until the playlist moves on.
+func (c *Controller) Play(name string, params Params) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if params == nil {
+		for i, entry := range c.playlist {
+			if entry.Name == name {
+				return c.jumpTo(i)
+			}
+		}
+	}
+
+	if c.calibrating {
+		return fmt.Errorf("calibration is running")
+	}
+
+	if err := ValidateAnimation(name, params); err != nil {
+		return err
+	}
+
+	c.showEntry(PlaylistEntry{Name: name, Animation: name, Params: params}, -1)
+	c.notifyEntryChanged()
+	return nil
 }
 
-func (c *Controller) createRandomMultiTwinkle(numColours int, saturationMin float64, saturationMax float64) (Animation, string) {
-	if numColours < 1 {
-		numColours = rand.Intn(8) + 2
+// PlayFavourite shows a favourite until the playlist moves on.
+func (c *Controller) PlayFavourite(name string, favourite Favourite) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.calibrating {
+		return fmt.Errorf("calibration is running")
 	}
 
-	twinkleChance := rand.Int31n(30) + 20
-	extraInfo := fmt.Sprintf("chance: 1:%d colours: ", twinkleChance)
-	backColours := make([]colorful.Color, numColours)
-	for i := 0; i < numColours; i++ {
-		backColours[i] = colorful.Hsl(rand.Float64()*360.0, util.RandomiseSaturation(saturationMin, saturationMax), 0.02)
+	c.showEntry(favourite.entry(name), -1)
+	c.notifyEntryChanged()
+	return nil
+}
+
+// SetBrightness scales the brightness of every frame, 1.0 is full brightness.
+func (c *Controller) SetBrightness(brightness float64) error {
+	if brightness < 0.0 || brightness > 1.0 {
+		return fmt.Errorf("brightness %0.2f should be between 0 and 1", brightness)
 	}
-	extraInfo += c.SprintColours(backColours)
 
-	return NewMultiTwinkle(twinkleChance, backColours, nil, c.runtimeMs), extraInfo
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.brightness = brightness
+	c.notifyStateChanged()
+	return nil
 }
 
-func (c *Controller) createRandomInfinityStripe() Animation {
-	return NewInfinityStripe(c.runtimeMs, 0.5, stripe.NewRandomStripeGeneratorVariableSaturation(SaturationMin, SaturationMax))
+// SetPower turns the lights off by sending black frames, or back on again.
+func (c *Controller) SetPower(power bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.power = power
+	c.notifyStateChanged()
 }
 
-func (c *Controller) createPaletteInfinityStripe(palette []colorful.Color) Animation {
-	return NewInfinityStripe(c.runtimeMs, 0.6, stripe.NewRandomStripeGenerator(palette))
+// SetSaturationMode chooses the range of saturations for the animations that are created next.
+func (c *Controller) SetSaturationMode(mode string) error {
+	saturation, ok := saturationModes[mode]
+	if !ok {
+		return fmt.Errorf("unknown saturation mode %q", mode)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.saturationMode = mode
+	c.saturation = saturation
+	c.notifyStateChanged()
+	return nil
 }
 
-func (c *Controller) createStreak(backColour colorful.Color) Animation {
-	return NewStreak(c.runtimeMs, 100, backColour)
+// SetSchedule turns the output on or off and caps the brightness for the Scheduler.
+func (c *Controller) SetSchedule(state ScheduleState) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.schedule = state
+	c.notifyStateChanged()
 }
 
-func (c *Controller) SprintColour(colour colorful.Color) string {
-	return fmt.Sprintf("{R: %0.3f, G: %0.3f, B: %0.3f}", colour.R, colour.G, colour.B)
+// SetCycling pauses or resumes cycling through the playlist.
+func (c *Controller) SetCycling(cycling bool) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.calibrating {
+		return fmt.Errorf("calibration is running")
+	}
+
+	c.cycling = cycling
+	if cycling {
+		// Give the current entry its full duration
+		c.notifyEntryChanged()
+	}
+	c.notifyStateChanged()
+
+	return nil
 }
 
-func (c *Controller) SprintColours(colours []colorful.Color) string {
-	colourCode := "[]colorful.Color{"
-	for i := 0; i < len(colours); i++ {
-		colourCode += c.SprintColour(colours[i])
-		if i < len(colours)-1 {
-			colourCode += ", "
-		}
+// SetPlaylist switches to a named playlist, starting from its first entry.
+func (c *Controller) SetPlaylist(name string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	playlist, ok := c.playlists[name]
+	if !ok {
+		return fmt.Errorf("unknown playlist %q", name)
 	}
-	colourCode += "}"
-
-	return colourCode
-}
-
-func (c *Controller) getAnimation() (Animation, string) {
-	brightPurple := colorful.Hcl(328.0, 1.0, 0.06)
-	brightPink := colorful.Color{R: 0.45, G: -0.54, B: 0.02}
-	brightOrange := colorful.Color{R: 0.23, G: 0.04, B: -0.87}
-	brightRed := colorful.Color{R: 0.8, G: 0.0, B: 0.00}
-	brightWhite := colorful.Color{R: 0.08, G: 0.08, B: 0.08}
-	brightBlue := colorful.Hcl(280.0, 1.0, 0.06)
-	brightGold := colorful.Hcl(95.0, 1.0, 0.06)
-
-	twinkleHighlight, _ := colorful.Hex("#808080")
-	gold, _ := colorful.Hex("#050401")
-	pink, _ := colorful.Hex("#100505")
-	purple, _ := colorful.Hex("#050005")
-	silver, _ := colorful.Hex("#030303")
-	blue, _ := colorful.Hex("#000005")
-	green, _ := colorful.Hex("#000500")
-	red, _ := colorful.Hex("#050000")
-	white, _ := colorful.Hex("#202020")
-
-	lightOrange := colorful.Hcl(88.0, 0.2, 0.04)
-	deepOrange := colorful.Hcl(89.0, 0.8, 0.04)
-	cream := colorful.Hcl(88.0, 0.04, 0.04)
-	turquoise := colorful.Hcl(185.0, 0.04, 0.02)
-	seventies := []colorful.Color{
-		lightOrange,
-		deepOrange,
-		cream,
-		turquoise,
-	}
-
-	extraInfo := ""
-	var animation Animation
-	switch c.animationPlaylist[c.animationIndex] {
-	case "streak:random":
-		animation = c.createStreak(blue)
-	case "twinkle:blue":
-		animation = c.createKnownTwinkle(twinkleHighlight, blue)
-	case "twinkle:pink":
-		animation = c.createKnownTwinkle(twinkleHighlight, pink)
-	case "twinkle:random":
-		animation, extraInfo = c.createRandomTwinkle(twinkleHighlight, SaturationMin, SaturationMax)
-	case "twinkle:silver":
-		animation = c.createKnownTwinkle(twinkleHighlight, silver)
-	case "twinkle:gold":
-		animation = c.createKnownTwinkle(twinkleHighlight, gold)
-	case "multi:random":
-		animation, extraInfo = c.createRandomMultiTwinkle(0, SaturationMin, SaturationMax)
-	case "multi:random2":
-		animation, extraInfo = c.createRandomMultiTwinkle(2, SaturationMin, SaturationMax)
-	case "multi:random3":
-		animation, extraInfo = c.createRandomMultiTwinkle(3, SaturationMin, SaturationMax)
-	case "multi:purplegoldblue":
-		animation = c.createMultiTwinkle([]colorful.Color{purple, gold, blue})
-	case "multi:pinksilverblue":
-		animation = c.createMultiTwinkle([]colorful.Color{pink, silver, blue})
-	case "multi:redgreengold":
-		animation = c.createMultiTwinkle([]colorful.Color{red, green, gold})
-	case "multi:redwhiteblue":
-		animation = c.createMultiTwinkle([]colorful.Color{red, white, blue})
-	case "rainbow:normal":
-		animation = c.createKnownRainbow()
-	case "rainbow:random":
-		animation = c.createRandomRainbow()
-	case "rainbow:fixed":
-		animation = c.createFixedRainbow()
-	case "gradient:rainbowstep":
-		animation = c.createGradientRandom(c.rainbowStepGradient, 1000)
-	case "gradient:pinkorangewhite":
-		gradient := c.createStripes([]colorful.Color{brightPink, brightOrange, brightWhite})
-		animation = c.createGradient(gradient, 310, -0.3)
-	case "gradient:purplegoldblue":
-		gradient := c.createStripes([]colorful.Color{brightPurple, brightGold, brightBlue})
-		animation = c.createGradient(gradient, 310, -0.3)
-	case "stripes:candycane":
-		gradient := c.createStripes([]colorful.Color{brightRed, brightWhite})
-		animation = c.createGradientRandom(gradient, 320)
-	case "stripes:random":
-		animation, extraInfo = c.createRandomStripes(0, SaturationMin, SaturationMax)
-	case "multi:pinkblue":
-		animation = c.createMultiTwinkle([]colorful.Color{
-			{R: 0.040, G: 0.000, B: 0.011},
-			{R: 0.000, G: 0.024, B: 0.040},
-			{R: 0.005, G: 0.000, B: 0.040}})
-	case "multi:monokai":
-		animation = c.createMultiTwinkle([]colorful.Color{
-			{R: 0.020, G: 0.040, B: 0.000},
-			{R: 0.012, G: 0.000, B: 0.040},
-			{R: 0.000, G: 0.040, B: 0.019},
-			{R: 0.040, G: 0.000, B: 0.005},
-			{R: 0.033, G: 0.040, B: 0.000},
-			{R: 0.040, G: 0.000, B: 0.010},
-			{R: 0.012, G: 0.040, B: 0.000}})
-	case "istripe:random":
-		animation = c.createRandomInfinityStripe()
-	case "istripe:70s":
-		animation = c.createPaletteInfinityStripe(seventies)
-	}
-
-	if len(extraInfo) > 0 {
-		log.Printf("Cycling to %s; %s", c.animationPlaylist[c.animationIndex], extraInfo)
+
+	if c.calibrating {
+		return fmt.Errorf("calibration is running")
+	}
+
+	log.Printf("Switching to playlist %s", name)
+	c.playlistName = name
+	c.playlist = playlist
+	c.animationIndex = 0
+	if c.animation == nil {
+		// There has to be an animation to start with
+		entry := c.currentEntry()
+		animation, info, err := c.getAnimation(entry, 0)
+		if err != nil {
+			return err
+		}
+		c.animation = animation
+		c.animationInfo = info
 	} else {
-		log.Printf("Cycling to %s", c.animationPlaylist[c.animationIndex])
+		c.startEntry()
 	}
 
-	return animation, extraInfo
+	c.notifyEntryChanged()
+	return nil
 }
 
-func (c *Controller) cycleAnimation() {
-	if c.cycling {
-		c.animationIndex++
-		c.animationIndex %= len(c.animationPlaylist)
-		c.nextAnimation, _ = c.getAnimation()
+// Status gets a snapshot of what the Controller is doing.
+func (c *Controller) Status() ControllerStatus {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	status := ControllerStatus{
+		Playlist:    c.playlistName,
+		Playlists:   make([]string, 0, len(c.playlists)),
+		Cycling:     c.cycling,
+		Calibrating: c.calibrating,
+		Power:       c.power,
+		Brightness:  c.brightness,
+		Saturation:  c.saturationMode,
+		Schedule:    c.schedule,
+		Current:     c.animationInfo,
+	}
+
+	for name := range c.playlists {
+		status.Playlists = append(status.Playlists, name)
+	}
+	sort.Strings(status.Playlists)
+
+	if c.nextAnimation != nil {
+		next := c.nextAnimationInfo
+		status.Next = &next
+	}
+
+	return status
+}
+
+func (c *Controller) currentDuration() time.Duration {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.currentEntry().Duration
+}
+
+func (c *Controller) setCalibrating(calibrating bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if calibrating == c.calibrating {
+		return
+	}
+
+	c.calibrating = calibrating
+	if calibrating {
+		c.cycling = false
+		c.animation = c.calibrate
+		c.nextAnimation = nil
+		c.animationInfo = AnimationInfo{Name: "calibration", Animation: "calibration", Index: -1}
+		c.notifyStateChanged()
+		fmt.Println("Started displaying calibration frames...")
+	} else {
+		// Pick up the pixel locations that the calibration has just stored
+		c.pixelMap = loadCalibratedPixelMap(c.layout)
+		c.cycling = true
+		c.startEntry()
+		c.notifyEntryChanged()
 	}
 }
 
 // Run causes the Controller to cycle through animations.
 func (c *Controller) Run() {
-	publishTimer := time.NewTicker(c.animationTime)
+	entryTimer := time.NewTimer(c.currentDuration())
 	for {
 		select {
-		case <-publishTimer.C:
+		case <-entryTimer.C:
 			c.cycleAnimation()
-		case start := <-c.calibrate.C:
-			if start {
-				c.cycling = false
-				c.animation = c.calibrate
-				fmt.Println("Started displaying calibration frames...")
-			} else {
-				c.cycling = true
-				c.cycleAnimation()
+			entryTimer.Reset(c.currentDuration())
+		case <-c.entryChanged:
+			if !entryTimer.Stop() {
+				select {
+				case <-entryTimer.C:
+				default:
+				}
 			}
+			entryTimer.Reset(c.currentDuration())
+		case start := <-c.calibrate.C:
+			c.setCalibrating(start)
 		}
 	}
 }
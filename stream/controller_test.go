@@ -0,0 +1,80 @@
+package stream
+
+import (
+	"testing"
+)
+
+// newTestController creates a Controller for a layout of pixels with some playlists. It isn't run, so frames
+// are only calculated when the test asks for them.
+func newTestController(t *testing.T, pixels int, seed int64, playlists map[string]Playlist, playlistName string) *Controller {
+	layout, err := NewLayout(Layout{Pixels: pixels})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var config Config
+	config.Playlists = playlists
+	validated, err := NewPlaylists(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewController(layout, 0, validated, playlistName, seed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// renderShow calculates frames at 40ms intervals, moving to the next entry part way through so that the
+// transition is rendered too.
+func renderShow(t *testing.T, c *Controller, count int) []*Frame {
+	frames := make([]*Frame, count)
+	for i := range frames {
+		if i == count/2 {
+			if err := c.Next(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		frames[i] = c.CalculateFrame(int64(i) * 40)
+	}
+
+	return frames
+}
+
+func sameFrames(a []*Frame, b []*Frame) bool {
+	for i := range a {
+		for j := range a[i].pixels {
+			if a[i].pixels[j] != b[i].pixels[j] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func TestControllerIsReproducibleFromItsSeed(t *testing.T) {
+	playlists := map[string]Playlist{
+		"random": {
+			{Animation: "twinkle"},
+			{Animation: "stripes", TransitionStyle: "dissolve"},
+			{Animation: "istripe"},
+			{Animation: "rainbow"},
+		},
+	}
+
+	for _, playlist := range []string{"random", defaultPlaylistName} {
+		first := renderShow(t, newTestController(t, 100, 42, playlists, playlist), 200)
+		second := renderShow(t, newTestController(t, 100, 42, playlists, playlist), 200)
+		if !sameFrames(first, second) {
+			t.Errorf("playlist %s rendered different frames from the same seed", playlist)
+		}
+
+		other := renderShow(t, newTestController(t, 100, 43, playlists, playlist), 200)
+		if sameFrames(first, other) {
+			t.Errorf("playlist %s rendered the same frames from different seeds", playlist)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package stream
+
+import (
+	"fmt"
+	"math"
+)
+
+const gammaLutSize = 4096
+
+// ColourCorrection converts the pixels in a Frame into device values using a gamma curve and per-channel
+// white balance. Temporal dithering carries the rounding error of each channel over to the next frame so that
+// very dim colours average out to values between the 8-bit steps.
+type ColourCorrection struct {
+	lut      []float64
+	balance  [3]float64
+	dither   bool
+	residual []float64
+}
+
+// NewColourCorrection creates an instance of a ColourCorrection from the output config.
+func NewColourCorrection(config Config, layout *Layout) (*ColourCorrection, error) {
+	output := config.Output
+	gamma := output.Gamma
+	if gamma == 0 {
+		gamma = 1.0
+	}
+	if gamma < 0 {
+		return nil, fmt.Errorf("gamma %0.2f must be positive", gamma)
+	}
+
+	cc := new(ColourCorrection)
+	cc.balance = [3]float64{1.0, 1.0, 1.0}
+	for i, b := range []*float64{output.Balance.Red, output.Balance.Green, output.Balance.Blue} {
+		if b != nil {
+			if *b < 0 || *b > 1 {
+				return nil, fmt.Errorf("colour balance %0.2f must be between 0 and 1", *b)
+			}
+			cc.balance[i] = *b
+		}
+	}
+
+	cc.lut = make([]float64, gammaLutSize)
+	for i := 0; i < gammaLutSize; i++ {
+		cc.lut[i] = math.Pow(float64(i)/float64(gammaLutSize-1), gamma) * 255.0
+	}
+
+	cc.dither = output.Dither
+	cc.residual = make([]float64, layout.Pixels*3)
+
+	return cc, nil
+}
+
+func (cc *ColourCorrection) lookup(v float64) float64 {
+	if v <= 0 {
+		return 0
+	} else if v >= 1 {
+		return cc.lut[gammaLutSize-1]
+	}
+
+	return cc.lut[int(v*float64(gammaLutSize-1)+0.5)]
+}
+
+func (cc *ColourCorrection) quantise(index int, v float64) byte {
+	if cc.dither {
+		v += cc.residual[index]
+	}
+
+	q := math.Floor(v + 0.5)
+	if q < 0 {
+		q = 0
+	} else if q > 255 {
+		q = 255
+	}
+
+	if cc.dither {
+		cc.residual[index] = v - q
+	}
+
+	return byte(q)
+}
+
+// Levels gets the corrected device level of each channel in a Frame, before they're rounded to 8 bits.
+func (cc *ColourCorrection) Levels(f *Frame) []float64 {
+	levels := make([]float64, len(f.pixels)*3)
+	for i, p := range f.pixels {
+		channels := [3]float64{p.R, p.G, p.B}
+		for j, v := range channels {
+			levels[(i*3)+j] = cc.lookup(v) * cc.balance[j]
+		}
+	}
+
+	return levels
+}
+
+// Quantise creates a copy of a Frame with its levels rounded to device values, the original Frame isn't
+// changed. Anything that scales the levels, like the PowerLimiter, has to do it before they're quantised so
+// that dithering carries over the error of the values that are actually sent.
+func (cc *ColourCorrection) Quantise(f *Frame, levels []float64) *Frame {
+	out := f.clone()
+	out.rgb = make([]byte, len(levels))
+	for i, v := range levels {
+		out.rgb[i] = cc.quantise(i, v)
+	}
+
+	return out
+}
+
+// Apply creates a copy of a Frame with corrected device values, the original Frame isn't changed.
+func (cc *ColourCorrection) Apply(f *Frame) *Frame {
+	return cc.Quantise(f, cc.Levels(f))
+}
@@ -0,0 +1,101 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func newTestCorrection(t *testing.T, pixels int, gamma float64, balance [3]float64, dither bool) *ColourCorrection {
+	var config Config
+	config.Output.Gamma = gamma
+	config.Output.Balance.Red = &balance[0]
+	config.Output.Balance.Green = &balance[1]
+	config.Output.Balance.Blue = &balance[2]
+	config.Output.Dither = dither
+
+	cc, err := NewColourCorrection(config, &Layout{Pixels: pixels})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cc
+}
+
+func newCorrectionFrame(colours ...colorful.Color) *Frame {
+	f := NewFrame(&Layout{Pixels: len(colours)})
+	copy(f.pixels, colours)
+	return f
+}
+
+func TestColourCorrection(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		gamma   float64
+		balance [3]float64
+		colour  colorful.Color
+		want    [3]byte
+	}{
+		{"gamma 1 black", 1.0, [3]float64{1, 1, 1}, colorful.Color{R: 0, G: 0, B: 0}, [3]byte{0, 0, 0}},
+		{"gamma 1 white", 1.0, [3]float64{1, 1, 1}, colorful.Color{R: 1, G: 1, B: 1}, [3]byte{255, 255, 255}},
+		{"gamma 1 levels", 1.0, [3]float64{1, 1, 1}, colorful.Color{R: 1.0 / 255, G: 128.0 / 255, B: 254.0 / 255},
+			[3]byte{1, 128, 254}},
+		{"gamma 2.2 mid-grey", 2.2, [3]float64{1, 1, 1}, colorful.Color{R: 128.0 / 255, G: 128.0 / 255, B: 128.0 / 255},
+			[3]byte{56, 56, 56}},
+		{"gamma 2.2 white", 2.2, [3]float64{1, 1, 1}, colorful.Color{R: 1, G: 1, B: 1}, [3]byte{255, 255, 255}},
+		{"half green", 1.0, [3]float64{1, 0.5, 1}, colorful.Color{R: 200.0 / 255, G: 200.0 / 255, B: 200.0 / 255},
+			[3]byte{200, 100, 200}},
+		{"no blue", 1.0, [3]float64{1, 1, 0}, colorful.Color{R: 1, G: 1, B: 1}, [3]byte{255, 255, 0}},
+	} {
+		cc := newTestCorrection(t, 1, test.gamma, test.balance, false)
+		f := newCorrectionFrame(test.colour)
+		got := cc.Apply(f).RGB()
+		if got[0] != test.want[0] || got[1] != test.want[1] || got[2] != test.want[2] {
+			t.Errorf("%s: got %v, expected %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestColourCorrectionGammaOneIsTheIdentity(t *testing.T) {
+	cc := newTestCorrection(t, 256, 1.0, [3]float64{1, 1, 1}, false)
+	colours := make([]colorful.Color, 256)
+	for i := range colours {
+		v := float64(i) / 255
+		colours[i] = colorful.Color{R: v, G: v, B: v}
+	}
+	f := newCorrectionFrame(colours...)
+
+	rgb := cc.Apply(f).RGB()
+	for i, v := range rgb {
+		if int(v) != i/3 {
+			t.Fatalf("level %d came out as %d", i/3, v)
+		}
+	}
+}
+
+func TestColourCorrectionDitherAveragesSubStepLevels(t *testing.T) {
+	const frames = 100
+
+	// The balance scales full brightness down to a fraction of the first step
+	for _, level := range []float64{0.3, 0.75, 2.5} {
+		for _, dither := range []bool{false, true} {
+			b := level / 255
+			cc := newTestCorrection(t, 1, 1.0, [3]float64{b, b, b}, dither)
+			f := newCorrectionFrame(colorful.Color{R: 1, G: 1, B: 1})
+
+			total := 0
+			for i := 0; i < frames; i++ {
+				total += int(cc.Apply(f).RGB()[0])
+			}
+
+			want := int(level*frames + 0.5)
+			if !dither {
+				want = int(level+0.5) * frames
+			}
+			if total < want-1 || total > want+1 {
+				t.Errorf("level %0.2f with dither %t averaged %0.2f over %d frames, expected %0.2f", level, dither,
+					float64(total)/frames, frames, float64(want)/frames)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package stream
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+const (
+	ddpDefaultPort    = "4048"
+	ddpHeaderLength   = 10
+	ddpMaxDataLength  = 1440 // 480 RGB pixels, keeps packets inside a standard MTU
+	ddpFlagsVersion1  = 0x40
+	ddpFlagsPush      = 0x01
+	ddpDataTypeRGB8   = 0x0B
+	ddpDestinationID  = 0x01 // The default output device
+	ddpSequenceMax    = 15
+	ddpSequenceUnused = 0
+)
+
+// DdpTransport sends frames using the Distributed Display Protocol, which is understood by controllers such
+// as WLED. DDP has no way to ACK frames.
+type DdpTransport struct {
+	conn     net.Conn
+	sequence uint8
+	buffer   []byte
+}
+
+// NewDdpTransport creates an instance of a DdpTransport that sends to address, the standard DDP port is used
+// when address doesn't have one.
+func NewDdpTransport(address string) (*DdpTransport, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, ddpDefaultPort)
+	}
+
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	t := new(DdpTransport)
+	t.conn = conn
+	t.sequence = ddpSequenceUnused
+	t.buffer = make([]byte, ddpHeaderLength+ddpMaxDataLength)
+	return t, nil
+}
+
+func (t *DdpTransport) nextSequence() uint8 {
+	t.sequence++
+	if t.sequence > ddpSequenceMax {
+		t.sequence = 1
+	}
+
+	return t.sequence
+}
+
+// Send splits a frame into as many packets as it needs and sets the push flag on the last one so that the
+// device displays all of the data together.
+func (t *DdpTransport) Send(f *Frame) error {
+	data := f.RGB()
+	sequence := t.nextSequence()
+	for offset := 0; offset < len(data); offset += ddpMaxDataLength {
+		length := len(data) - offset
+		last := true
+		if length > ddpMaxDataLength {
+			length = ddpMaxDataLength
+			last = false
+		}
+
+		packet := t.buffer[:ddpHeaderLength+length]
+		packet[0] = ddpFlagsVersion1
+		if last {
+			packet[0] |= ddpFlagsPush
+		}
+		packet[1] = sequence
+		packet[2] = ddpDataTypeRGB8
+		packet[3] = ddpDestinationID
+		binary.BigEndian.PutUint32(packet[4:], uint32(offset))
+		binary.BigEndian.PutUint16(packet[8:], uint16(length))
+		copy(packet[ddpHeaderLength:], data[offset:offset+length])
+
+		if _, err := t.conn.Write(packet); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetAckHandler does nothing because DDP doesn't support ACKs.
+func (t *DdpTransport) SetAckHandler(handler AckHandler) {
+}
@@ -0,0 +1,54 @@
+package stream
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestDdpPacketLayout(t *testing.T) {
+	conn, address := listenUDP(t)
+	_, f := testFrame(t, 600)
+
+	transport, err := NewDdpTransport(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 600 pixels are 1800 bytes, which takes two packets
+	for i := 0; i < 2; i++ {
+		if err := transport.Send(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	packets := receivePackets(t, conn, 4)
+
+	for i, want := range []struct {
+		push     bool
+		sequence uint8
+		offset   uint32
+		length   uint16
+	}{{false, 1, 0, ddpMaxDataLength}, {true, 1, ddpMaxDataLength, 360}, {false, 2, 0, ddpMaxDataLength}, {true, 2, ddpMaxDataLength, 360}} {
+		p := packets[i]
+		if p[0]&ddpFlagsVersion1 == 0 {
+			t.Errorf("packet %d doesn't have the version 1 flag", i)
+		}
+		if push := p[0]&ddpFlagsPush != 0; push != want.push {
+			t.Errorf("packet %d has push %t, expected %t", i, push, want.push)
+		}
+		if p[1] != want.sequence {
+			t.Errorf("packet %d has sequence %d, expected %d", i, p[1], want.sequence)
+		}
+		if p[2] != ddpDataTypeRGB8 || p[3] != ddpDestinationID {
+			t.Errorf("packet %d has data type %#x and destination %#x", i, p[2], p[3])
+		}
+		if offset := binary.BigEndian.Uint32(p[4:]); offset != want.offset {
+			t.Errorf("packet %d has offset %d, expected %d", i, offset, want.offset)
+		}
+		if length := binary.BigEndian.Uint16(p[8:]); length != want.length || len(p) != ddpHeaderLength+int(length) {
+			t.Errorf("packet %d has length %d in %d bytes, expected %d", i, length, len(p), want.length)
+		}
+		if pixel := int(want.offset / 3); p[ddpHeaderLength] != byte(pixel%256) {
+			t.Errorf("packet %d starts with pixel %d, expected %d", i, p[ddpHeaderLength], pixel%256)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package stream
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	dmxChannels           = 512
+	dmxPixelsPerUniverse  = dmxChannels / 3
+	dmxMaxUniverse        = 63999
+	dmxDefaultSourceName  = "ledtx"
+	dmxDefaultE131Start   = 1
+	dmxDefaultArtNetStart = 0
+)
+
+// A UniverseMapping places a range of pixels into a DMX universe starting at a channel.
+type UniverseMapping struct {
+	Universe int `yaml:"universe"`
+	Start    int `yaml:"start"`   // The first pixel index
+	Count    int `yaml:"count"`   // The number of pixels, each pixel uses 3 channels
+	Channel  int `yaml:"channel"` // The first channel, numbered from 1
+}
+
+func (m UniverseMapping) lastChannel() int {
+	return m.Channel - 1 + (m.Count * 3)
+}
+
+type dmxUniverse struct {
+	universe int
+	sequence uint8
+	mappings []UniverseMapping
+	data     [dmxChannels]byte
+}
+
+// dmxUniverses holds the channel data for every universe that a frame is split into.
+type dmxUniverses []*dmxUniverse
+
+// newDmxUniverses validates the configured mappings. If none are configured then the frame is split into
+// consecutive universes of 170 pixels starting at firstUniverse.
+func newDmxUniverses(mappings []UniverseMapping, layout *Layout, firstUniverse int) (dmxUniverses, error) {
+	if len(mappings) == 0 {
+		for start, u := 0, firstUniverse; start < layout.Pixels; start, u = start+dmxPixelsPerUniverse, u+1 {
+			count := dmxPixelsPerUniverse
+			if start+count > layout.Pixels {
+				count = layout.Pixels - start
+			}
+			mappings = append(mappings, UniverseMapping{Universe: u, Start: start, Count: count, Channel: 1})
+		}
+	}
+
+	byUniverse := make(map[int]*dmxUniverse)
+	for _, m := range mappings {
+		if m.Channel == 0 {
+			m.Channel = 1
+		}
+
+		if m.Universe < firstUniverse || m.Universe > dmxMaxUniverse {
+			return nil, fmt.Errorf("universe %d is out of range", m.Universe)
+		}
+
+		if m.Count <= 0 || m.Start < 0 || m.Start+m.Count > layout.Pixels {
+			return nil, fmt.Errorf("pixels %d-%d mapped to universe %d are outside of the %d pixels",
+				m.Start, m.Start+m.Count-1, m.Universe, layout.Pixels)
+		}
+
+		if m.Channel < 1 || m.lastChannel() > dmxChannels {
+			return nil, fmt.Errorf("%d pixels starting at channel %d don't fit in universe %d",
+				m.Count, m.Channel, m.Universe)
+		}
+
+		u, ok := byUniverse[m.Universe]
+		if !ok {
+			u = &dmxUniverse{universe: m.Universe}
+			byUniverse[m.Universe] = u
+		}
+
+		for _, other := range u.mappings {
+			if m.Channel <= other.lastChannel() && other.Channel <= m.lastChannel() {
+				return nil, fmt.Errorf("pixels %d-%d on channels %d-%d overlap pixels %d-%d on channels %d-%d "+
+					"in universe %d", m.Start, m.Start+m.Count-1, m.Channel, m.lastChannel(), other.Start,
+					other.Start+other.Count-1, other.Channel, other.lastChannel(), m.Universe)
+			}
+		}
+		u.mappings = append(u.mappings, m)
+	}
+
+	universes := make(dmxUniverses, 0, len(byUniverse))
+	for _, u := range byUniverse {
+		universes = append(universes, u)
+	}
+	sort.Slice(universes, func(i, j int) bool { return universes[i].universe < universes[j].universe })
+
+	return universes, nil
+}
+
+// fill copies RGB data from a frame into the channels of each universe. The frame must have every pixel that's
+// mapped, it won't if it was recorded with a different layout.
+func (universes dmxUniverses) fill(rgb []byte) error {
+	for _, u := range universes {
+		for _, m := range u.mappings {
+			end := (m.Start + m.Count) * 3
+			if end > len(rgb) {
+				return fmt.Errorf("universe %d needs pixels %d-%d but the frame only has %d pixels",
+					u.universe, m.Start, m.Start+m.Count-1, len(rgb)/3)
+			}
+			copy(u.data[m.Channel-1:], rgb[m.Start*3:end])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package stream
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// listenUDP starts a listener on the loopback address for a transport to send to.
+func listenUDP(t *testing.T) (net.PacketConn, string) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, conn.LocalAddr().String()
+}
+
+// receivePackets waits for a number of packets to arrive.
+func receivePackets(t *testing.T, conn net.PacketConn, count int) [][]byte {
+	packets := make([][]byte, count)
+	for i := range packets {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		buffer := make([]byte, 2048)
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			t.Fatalf("failed to receive packet %d. %s", i, err)
+		}
+		packets[i] = buffer[:n]
+	}
+
+	return packets
+}
+
+// testFrame makes a frame where the red channel of each pixel is its index, so the pixels can be told apart.
+func testFrame(t *testing.T, pixels int) (*Layout, *Frame) {
+	layout, err := NewLayout(Layout{Pixels: pixels})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFrame(layout)
+	for i := range f.pixels {
+		f.pixels[i] = colorful.Color{R: float64(i%256) / 255.0, G: 1.0, B: 0.0}
+	}
+
+	return layout, f
+}
+
+func TestDmxUniversesRejectShortFrames(t *testing.T) {
+	layout, _ := testFrame(t, 200)
+	universes, err := newDmxUniverses(nil, layout, dmxDefaultE131Start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, short := testFrame(t, 100)
+	if err := universes.fill(short.RGB()); err == nil {
+		t.Fatal("filling 200 pixels of universes from a 100 pixel frame should fail")
+	}
+}
+
+func TestDmxUniversesRejectOverlappingMappings(t *testing.T) {
+	layout, _ := testFrame(t, 200)
+	for _, tc := range []struct {
+		mappings []UniverseMapping
+		valid    bool
+	}{
+		{[]UniverseMapping{
+			{Universe: 1, Start: 0, Count: 10, Channel: 1},
+			{Universe: 1, Start: 10, Count: 10, Channel: 31},
+		}, true},
+		{[]UniverseMapping{
+			{Universe: 1, Start: 0, Count: 10, Channel: 1},
+			{Universe: 2, Start: 10, Count: 10, Channel: 1},
+		}, true},
+		{[]UniverseMapping{
+			{Universe: 1, Start: 0, Count: 10, Channel: 1},
+			{Universe: 1, Start: 10, Count: 10, Channel: 30},
+		}, false},
+		{[]UniverseMapping{
+			{Universe: 1, Start: 0, Count: 10, Channel: 100},
+			{Universe: 1, Start: 10, Count: 50, Channel: 1},
+		}, false},
+	} {
+		_, err := newDmxUniverses(tc.mappings, layout, dmxDefaultE131Start)
+		if tc.valid && err != nil {
+			t.Errorf("mappings %+v should be allowed: %s", tc.mappings, err)
+		} else if !tc.valid && err == nil {
+			t.Errorf("mappings %+v overlap and should be rejected", tc.mappings)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package stream
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+const (
+	e131Port             = 5568
+	e131HeaderLength     = 126
+	e131PacketLength     = e131HeaderLength + dmxChannels
+	e131RootVector       = 0x00000004
+	e131FramingVector    = 0x00000002
+	e131DmpVector        = 0x02
+	e131DmpAddressType   = 0xa1
+	e131DefaultPriority  = 100
+	e131SourceNameLength = 64
+	e131FlagsLength      = 0x7000
+)
+
+var e131PacketIdentifier = []byte{0x41, 0x53, 0x43, 0x2d, 0x45, 0x31, 0x2e, 0x31, 0x37, 0x00, 0x00, 0x00}
+
+// E131Transport sends frames as streaming ACN (E1.31) packets, one per universe. Packets are multicast unless
+// a unicast address is configured. E1.31 has no way to ACK frames.
+type E131Transport struct {
+	conn         net.PacketConn
+	universes    dmxUniverses
+	destinations []net.Addr // The address for each universe
+	cid          [16]byte
+	sourceName   string
+	priority     uint8
+	packet       []byte
+}
+
+// NewE131Transport creates an instance of an E131Transport.
+func NewE131Transport(config Config, layout *Layout) (*E131Transport, error) {
+	universes, err := newDmxUniverses(config.Transport.Dmx.Universes, layout, dmxDefaultE131Start)
+	if err != nil {
+		return nil, err
+	}
+
+	destinations, err := e131Destinations(config.Transport.Dmx.Address, universes)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+
+	t := new(E131Transport)
+	t.conn = conn
+	t.universes = universes
+	t.destinations = destinations
+	t.sourceName = config.Transport.Dmx.Source
+	if t.sourceName == "" {
+		t.sourceName = dmxDefaultSourceName
+	}
+	t.priority = e131DefaultPriority
+	if config.Transport.Dmx.Priority > 0 {
+		t.priority = uint8(config.Transport.Dmx.Priority)
+	}
+
+	// Receivers expect the CID to stay the same for a source, so derive it from the name
+	t.cid = md5.Sum([]byte(t.sourceName))
+	t.packet = make([]byte, e131PacketLength)
+
+	return t, nil
+}
+
+// e131Destinations gets the address to send each universe to. Without a unicast address each universe has its
+// own multicast group.
+func e131Destinations(address string, universes dmxUniverses) ([]net.Addr, error) {
+	destinations := make([]net.Addr, len(universes))
+	if address == "" {
+		for i, u := range universes {
+			destinations[i] = &net.UDPAddr{IP: net.IPv4(239, 255, byte(u.universe>>8), byte(u.universe)), Port: e131Port}
+		}
+
+		return destinations, nil
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, fmt.Sprint(e131Port))
+	}
+
+	addr, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range destinations {
+		destinations[i] = addr
+	}
+
+	return destinations, nil
+}
+
+func (t *E131Transport) buildPacket(u *dmxUniverse) []byte {
+	p := t.packet
+
+	// Root layer
+	binary.BigEndian.PutUint16(p[0:], 0x0010) // Preamble size
+	binary.BigEndian.PutUint16(p[2:], 0x0000) // Postamble size
+	copy(p[4:16], e131PacketIdentifier)
+	binary.BigEndian.PutUint16(p[16:], e131FlagsLength|uint16(e131PacketLength-16))
+	binary.BigEndian.PutUint32(p[18:], e131RootVector)
+	copy(p[22:38], t.cid[:])
+
+	// Framing layer
+	binary.BigEndian.PutUint16(p[38:], e131FlagsLength|uint16(e131PacketLength-38))
+	binary.BigEndian.PutUint32(p[40:], e131FramingVector)
+	for i := 44; i < 44+e131SourceNameLength; i++ {
+		p[i] = 0
+	}
+	copy(p[44:44+e131SourceNameLength-1], t.sourceName)
+	p[108] = t.priority
+	binary.BigEndian.PutUint16(p[109:], 0) // Synchronisation address
+	p[111] = u.sequence
+	p[112] = 0 // Options
+	binary.BigEndian.PutUint16(p[113:], uint16(u.universe))
+
+	// DMP layer
+	binary.BigEndian.PutUint16(p[115:], e131FlagsLength|uint16(e131PacketLength-115))
+	p[117] = e131DmpVector
+	p[118] = e131DmpAddressType
+	binary.BigEndian.PutUint16(p[119:], 0x0000)        // First property address
+	binary.BigEndian.PutUint16(p[121:], 0x0001)        // Address increment
+	binary.BigEndian.PutUint16(p[123:], 1+dmxChannels) // Property value count, including the start code
+	p[125] = 0                                         // DMX start code
+	copy(p[e131HeaderLength:], u.data[:])
+
+	return p
+}
+
+// Send splits a frame into universes and sends a packet for each one.
+func (t *E131Transport) Send(f *Frame) error {
+	if err := t.universes.fill(f.RGB()); err != nil {
+		return err
+	}
+
+	for i, u := range t.universes {
+		if _, err := t.conn.WriteTo(t.buildPacket(u), t.destinations[i]); err != nil {
+			return err
+		}
+		u.sequence++
+	}
+
+	return nil
+}
+
+// SetAckHandler does nothing because E1.31 doesn't support ACKs.
+func (t *E131Transport) SetAckHandler(handler AckHandler) {
+}
@@ -0,0 +1,76 @@
+package stream
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestE131PacketLayout(t *testing.T) {
+	conn, address := listenUDP(t)
+	layout, f := testFrame(t, 200)
+
+	var config Config
+	config.Transport.Dmx.Address = address
+	config.Transport.Dmx.Source = "test"
+	transport, err := NewE131Transport(config, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 200 pixels take two universes, send two frames to see the sequence change
+	for i := 0; i < 2; i++ {
+		if err := transport.Send(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	packets := receivePackets(t, conn, 4)
+
+	for i, p := range packets {
+		if len(p) != e131PacketLength {
+			t.Fatalf("packet %d is %d bytes, expected %d", i, len(p), e131PacketLength)
+		}
+		if string(p[4:16]) != string(e131PacketIdentifier) {
+			t.Errorf("packet %d has identifier %q", i, p[4:16])
+		}
+		if v := binary.BigEndian.Uint32(p[18:]); v != e131RootVector {
+			t.Errorf("packet %d has root vector %d", i, v)
+		}
+		if v := binary.BigEndian.Uint32(p[40:]); v != e131FramingVector {
+			t.Errorf("packet %d has framing vector %d", i, v)
+		}
+		if name := string(p[44:48]); name != "test" || p[48] != 0 {
+			t.Errorf("packet %d has source name %q", i, p[44:108])
+		}
+		if p[108] != e131DefaultPriority {
+			t.Errorf("packet %d has priority %d", i, p[108])
+		}
+		if p[117] != e131DmpVector || p[118] != e131DmpAddressType {
+			t.Errorf("packet %d has DMP vector %#x and address type %#x", i, p[117], p[118])
+		}
+		if count := binary.BigEndian.Uint16(p[123:]); count != 1+dmxChannels {
+			t.Errorf("packet %d has %d property values", i, count)
+		}
+		if p[125] != 0 {
+			t.Errorf("packet %d has start code %d", i, p[125])
+		}
+	}
+
+	for i, want := range []struct {
+		universe   uint16
+		firstPixel byte
+	}{{1, 0}, {2, 170}, {1, 0}, {2, 170}} {
+		p := packets[i]
+		if universe := binary.BigEndian.Uint16(p[113:]); universe != want.universe {
+			t.Errorf("packet %d is for universe %d, expected %d", i, universe, want.universe)
+		}
+		if p[e131HeaderLength] != want.firstPixel || p[e131HeaderLength+1] != 255 {
+			t.Errorf("packet %d starts with %v, expected pixel %d", i, p[e131HeaderLength:e131HeaderLength+3], want.firstPixel)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if first, second := packets[i][111], packets[i+2][111]; second != first+1 {
+			t.Errorf("universe %d sequence went from %d to %d", i+1, first, second)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package stream
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const defaultFavouritesPath = "favourites.yaml"
+
+// A Favourite is a snapshot of an animation with all of its params and the seed, so it looks the same
+// every time that it's shown.
+type Favourite struct {
+	Animation string    `yaml:"animation" json:"animation"`
+	Params    Params    `yaml:"params" json:"params"`
+	Seed      int64     `yaml:"seed" json:"seed"`
+	Saved     time.Time `yaml:"saved" json:"saved"`
+}
+
+// entry makes a playlist entry that shows the Favourite.
+func (f Favourite) entry(name string) PlaylistEntry {
+	seed := f.Seed
+	return PlaylistEntry{Name: name, Animation: f.Animation, Params: f.Params, Seed: &seed}
+}
+
+// Favourites are the favourites that have been saved to a YAML file by name.
+type Favourites struct {
+	path       string
+	favourites map[string]Favourite
+	lock       sync.Mutex
+}
+
+// NewFavourites loads the favourites from a file, which doesn't need to exist yet.
+func NewFavourites(path string) (*Favourites, error) {
+	f := new(Favourites)
+	f.path = path
+	if f.path == "" {
+		f.path = defaultFavouritesPath
+	}
+	f.favourites = make(map[string]Favourite)
+
+	data, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return f, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, &f.favourites); err != nil {
+		return nil, fmt.Errorf("failed to read favourites from %s. %s", f.path, err)
+	}
+
+	for name, favourite := range f.favourites {
+		if err := ValidateAnimation(favourite.Animation, favourite.Params); err != nil {
+			return nil, fmt.Errorf("favourite %q is invalid. %s", name, err)
+		}
+	}
+
+	return f, nil
+}
+
+// Get finds a favourite by name.
+func (f *Favourites) Get(name string) (Favourite, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	favourite, ok := f.favourites[name]
+	return favourite, ok
+}
+
+// Names gets the names of the favourites sorted by name.
+func (f *Favourites) Names() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	names := make([]string, 0, len(f.favourites))
+	for name := range f.favourites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Save snapshots an animation as a favourite, replacing any favourite with the same name, and writes the file.
+func (f *Favourites) Save(name string, info AnimationInfo) (Favourite, error) {
+	if name == "" {
+		return Favourite{}, fmt.Errorf("a favourite needs a name")
+	}
+
+	if err := ValidateAnimation(info.Animation, info.Params); err != nil {
+		return Favourite{}, fmt.Errorf("%s can't be saved as a favourite. %s", info.Name, err)
+	}
+
+	params := make(Params, len(info.Params))
+	for k, v := range info.Params {
+		params[k] = v
+	}
+	favourite := Favourite{Animation: info.Animation, Params: params, Seed: info.Seed, Saved: time.Now()}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	previous, replacing := f.favourites[name]
+	f.favourites[name] = favourite
+	if err := f.write(); err != nil {
+		if replacing {
+			f.favourites[name] = previous
+		} else {
+			delete(f.favourites, name)
+		}
+		return Favourite{}, err
+	}
+
+	return favourite, nil
+}
+
+// write replaces the file in one go so that it isn't left half written.
+func (f *Favourites) write() error {
+	data, err := yaml.Marshal(f.favourites)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), ".favourites")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
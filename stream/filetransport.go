@@ -0,0 +1,42 @@
+package stream
+
+import (
+	"os"
+	"sync"
+)
+
+// FileTransport appends each frame to a file. Frames carry their own pixel count so they can be read back
+// one after another.
+type FileTransport struct {
+	file *os.File
+	lock sync.Mutex
+}
+
+// NewFileTransport creates an instance of a FileTransport that writes to filePath, replacing any existing file.
+func NewFileTransport(filePath string) (*FileTransport, error) {
+	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0664)
+	if err != nil {
+		return nil, err
+	}
+
+	t := new(FileTransport)
+	t.file = f
+	return t, nil
+}
+
+// Send appends a frame to the file.
+func (t *FileTransport) Send(f *Frame) error {
+	b, err := f.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	_, err = t.file.Write(b)
+	return err
+}
+
+// SetAckHandler does nothing because a file can't ACK frames.
+func (t *FileTransport) SetAckHandler(handler AckHandler) {
+}
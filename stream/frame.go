@@ -2,44 +2,118 @@ package stream
 
 import (
 	"encoding/binary"
+	"fmt"
 
 	"github.com/lucasb-eyer/go-colorful"
 )
 
-const numPixels = 600
-
 // Frame represents a frame of RGB pixels to display on an ledrx device.
 type Frame struct {
 	ackID  uint8
-	pixels [numPixels]colorful.Color
+	layout *Layout
+	pixels []colorful.Color
+	rgb    []byte // Device values that have already been corrected, nil if they haven't
 }
 
 // NewFrame creates a new Frame instance.
-func NewFrame() *Frame {
+func NewFrame(layout *Layout) *Frame {
 	f := new(Frame)
 	f.ackID = 0 // No signal by default
+	f.layout = layout
+	f.pixels = make([]colorful.Color, layout.Pixels)
 	return f
 }
 
-// InterpolateFrame merges two frames.
-func (f *Frame) InterpolateFrame(f2 *Frame, transitionPoint float64) *Frame {
-	out := NewFrame()
-	for i := 0; i < len(f.pixels); i++ {
-		out.pixels[i] = f.pixels[i].BlendHcl(f2.pixels[i], transitionPoint)
+// Len gets the number of pixels in the Frame.
+func (f *Frame) Len() int {
+	return len(f.pixels)
+}
+
+// Segments gets the segments that the Frame is divided into.
+func (f *Frame) Segments() []Segment {
+	return f.layout.Segments
+}
+
+// SegmentPixels gets the pixels that belong to a Segment, writes to the slice update the Frame.
+func (f *Frame) SegmentPixels(s Segment) []colorful.Color {
+	return f.pixels[s.Start:s.End()]
+}
+
+// clone makes a copy of the Frame that can be modified independently.
+func (f *Frame) clone() *Frame {
+	out := NewFrame(f.layout)
+	out.ackID = f.ackID
+	copy(out.pixels, f.pixels)
+	if f.rgb != nil {
+		out.rgb = make([]byte, len(f.rgb))
+		copy(out.rgb, f.rgb)
 	}
 
 	return out
 }
 
-// MarshalBinary converts a Frame into binary data.
-func (f *Frame) MarshalBinary() (data []byte, err error) {
-	data = make([]byte, 3, (numPixels*3)+3)
-	data[0] = f.ackID
-	binary.LittleEndian.PutUint16(data[1:], numPixels)
+// scale makes a copy of the Frame with every pixel's brightness multiplied by a factor.
+func (f *Frame) scale(factor float64) *Frame {
+	out := NewFrame(f.layout)
+	out.ackID = f.ackID
+	for i, p := range f.pixels {
+		out.pixels[i] = colorful.Color{R: p.R * factor, G: p.G * factor, B: p.B * factor}
+	}
+
+	return out
+}
+
+// RGB converts the pixels in a Frame into 8-bit RGB triplets. Pixels are converted linearly unless the Frame
+// has been through a ColourCorrection.
+func (f *Frame) RGB() []byte {
+	if f.rgb != nil {
+		return f.rgb
+	}
+
+	data := make([]byte, 0, len(f.pixels)*3)
 	for _, p := range f.pixels {
 		r, g, b := p.Clamped().RGB255()
 		data = append(data, r, g, b)
 	}
 
+	return data
+}
+
+// MarshalBinary converts a Frame into binary data.
+func (f *Frame) MarshalBinary() (data []byte, err error) {
+	numPixels := len(f.pixels)
+	data = make([]byte, 3, (numPixels*3)+3)
+	data[0] = f.ackID
+	binary.LittleEndian.PutUint16(data[1:], uint16(numPixels))
+	data = append(data, f.RGB()...)
+
 	return data, nil
 }
+
+// UnmarshalBinary reads a Frame from the binary data produced by MarshalBinary. The device values are kept
+// exactly as they are so they won't be corrected again.
+func (f *Frame) UnmarshalBinary(data []byte) error {
+	if len(data) < 3 {
+		return fmt.Errorf("frame data is too short")
+	}
+
+	numPixels := int(binary.LittleEndian.Uint16(data[1:]))
+	if len(data) != 3+(numPixels*3) {
+		return fmt.Errorf("frame data has %d bytes for %d pixels", len(data), numPixels)
+	}
+
+	f.ackID = data[0]
+	f.layout = &Layout{Pixels: numPixels, Segments: []Segment{{Name: defaultSegmentName, Start: 0, Length: numPixels}}}
+	f.rgb = make([]byte, numPixels*3)
+	copy(f.rgb, data[3:])
+	f.pixels = make([]colorful.Color, numPixels)
+	for i := 0; i < numPixels; i++ {
+		f.pixels[i] = colorful.Color{
+			R: float64(f.rgb[i*3]) / 255.0,
+			G: float64(f.rgb[(i*3)+1]) / 255.0,
+			B: float64(f.rgb[(i*3)+2]) / 255.0,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultFrameRate = 1000.0 / 21.0
+
+// FrameStats describes how well the frame rate is being kept up.
+type FrameStats struct {
+	FrameRate     float64       `json:"frameRate"`
+	Sent          uint64        `json:"sent"`
+	Dropped       uint64        `json:"dropped"`
+	LastFrameTime time.Duration `json:"lastFrameTime"`
+	MaxFrameTime  time.Duration `json:"maxFrameTime"`
+}
+
+// A FrameScheduler paces frames against the monotonic clock. Every frame is due at a fixed offset from the
+// start so that slow frames don't make the animations drift, if a frame is so late that the next one is
+// already due then the frames in between are dropped.
+type FrameScheduler struct {
+	frameRate float64
+	frameTime time.Duration
+	start     time.Time
+	frame     int64
+	stats     FrameStats
+	lock      sync.Mutex
+
+	// The clock, tests replace it so that they don't have to wait
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// NewFrameScheduler creates an instance of a FrameScheduler.
+func NewFrameScheduler(frameRate float64) *FrameScheduler {
+	if frameRate <= 0 {
+		frameRate = defaultFrameRate
+	}
+
+	fs := new(FrameScheduler)
+	fs.frameRate = frameRate
+	fs.frameTime = time.Duration(float64(time.Second) / frameRate)
+	fs.now = time.Now
+	fs.sleep = time.Sleep
+	fs.start = fs.now()
+	fs.frame = 0
+	fs.stats.FrameRate = frameRate
+	return fs
+}
+
+// Start (or restart) the clock, the first frame is due straight away.
+func (fs *FrameScheduler) Start() {
+	fs.start = fs.now()
+	fs.frame = -1
+}
+
+// FrameRate gets the number of frames per second that the scheduler is aiming for.
+func (fs *FrameScheduler) FrameRate() float64 {
+	return fs.frameRate
+}
+
+// Wait blocks until the next frame is due and gets the time elapsed since the scheduler started.
+func (fs *FrameScheduler) Wait() time.Duration {
+	fs.frame++
+	deadline := time.Duration(fs.frame) * fs.frameTime
+	elapsed := fs.now().Sub(fs.start)
+
+	if behind := int64((elapsed - deadline) / fs.frameTime); behind > 0 {
+		// Skip the frames that we've missed rather than trying to send them all at once
+		fs.frame += behind
+		deadline = time.Duration(fs.frame) * fs.frameTime
+
+		fs.lock.Lock()
+		fs.stats.Dropped += uint64(behind)
+		fs.lock.Unlock()
+	}
+
+	if wait := deadline - elapsed; wait > 0 {
+		fs.sleep(wait)
+	}
+
+	return fs.now().Sub(fs.start)
+}
+
+// Elapsed gets the time elapsed since the scheduler started.
+func (fs *FrameScheduler) Elapsed() time.Duration {
+	return fs.now().Sub(fs.start)
+}
+
+// FrameSent records how long it took to calculate and send a frame.
+func (fs *FrameScheduler) FrameSent(frameTime time.Duration) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	fs.stats.Sent++
+	fs.stats.LastFrameTime = frameTime
+	if frameTime > fs.stats.MaxFrameTime {
+		fs.stats.MaxFrameTime = frameTime
+	}
+}
+
+// Stats gets a snapshot of the frame statistics.
+func (fs *FrameScheduler) Stats() FrameStats {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	return fs.stats
+}
@@ -0,0 +1,52 @@
+package stream
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestFrameScheduler makes a scheduler whose clock only moves when it sleeps or the test advances it.
+func newTestFrameScheduler(frameRate float64) (*FrameScheduler, *time.Time) {
+	now := at(16, 12, 0)
+	fs := NewFrameScheduler(frameRate)
+	fs.now = func() time.Time { return now }
+	fs.sleep = func(d time.Duration) { now = now.Add(d) }
+	fs.Start()
+	return fs, &now
+}
+
+func TestFrameSchedulerDoesNotDrift(t *testing.T) {
+	fs, now := newTestFrameScheduler(100)
+
+	// Frames that take different amounts of time to render are all due on the 10ms grid
+	for i, render := range []time.Duration{3, 7, 1, 9, 0, 5} {
+		if got, want := fs.Wait(), time.Duration(i)*10*time.Millisecond; got != want {
+			t.Fatalf("frame %d was sent at %s, expected %s", i, got, want)
+		}
+		*now = now.Add(render * time.Millisecond)
+	}
+
+	if dropped := fs.Stats().Dropped; dropped != 0 {
+		t.Errorf("%d frames were dropped, none should have been", dropped)
+	}
+}
+
+func TestFrameSchedulerDropsFramesWhenRenderingIsSlow(t *testing.T) {
+	fs, now := newTestFrameScheduler(100)
+	fs.Wait()
+	fs.Wait()
+
+	// Frame 1 takes 35ms so frames 2 and 3 are missed, frame 4 goes straight away
+	*now = now.Add(35 * time.Millisecond)
+	if got, want := fs.Wait(), 45*time.Millisecond; got != want {
+		t.Errorf("the late frame was sent at %s, expected %s", got, want)
+	}
+	if dropped := fs.Stats().Dropped; dropped != 2 {
+		t.Errorf("%d frames were dropped, expected 2", dropped)
+	}
+
+	// Then it's back on the schedule
+	if got, want := fs.Wait(), 50*time.Millisecond; got != want {
+		t.Errorf("the next frame was sent at %s, expected %s", got, want)
+	}
+}
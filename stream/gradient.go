@@ -13,6 +13,61 @@ type GradientTable []struct {
 	Pos        float64
 }
 
+// rainbowGradient blends through the colours of the rainbow.
+var rainbowGradient = GradientTable{
+	{0.0, 1.0, 0.0},
+	{6.0, 1.0, 0.04},   // Pink
+	{87.0, 1.0, 0.14},  // Red
+	{88.0, 1.0, 0.28},  // Orange
+	{98.0, 1.0, 0.42},  // Yellow
+	{180.0, 1.0, 0.56}, // Green
+	{190.0, 1.0, 0.70}, // Turquiose
+	{320.0, 1.0, 0.84}, // Blue
+	{328.0, 1.0, 0.91}, // Violet
+	{360.0, 1.0, 1.0},  // Pink wrap
+}
+
+// rainbowStepGradient has solid bands for each colour of the rainbow.
+var rainbowStepGradient = GradientTable{
+	{80.0, 1.0, 0.0},    // Red
+	{80.0, 1.0, 0.167},  // Red
+	{86.0, 1.0, 0.167},  // Orange
+	{86.0, 1.0, 0.333},  // Orange
+	{95.0, 1.0, 0.333},  // Yellow
+	{95.0, 1.0, 0.5},    // Yellow
+	{160.0, 1.0, 0.5},   // Green
+	{160.0, 1.0, 0.666}, // Green
+	{280.0, 1.0, 0.666}, // Blue
+	{280.0, 1.0, 0.833}, // Blue
+	{328.0, 1.0, 0.833}, // Violet
+	{328.0, 1.0, 1.0},   // Violet
+}
+
+// NewStripeGradient creates a GradientTable with a solid, equally sized stripe for each colour.
+func NewStripeGradient(stripeColours []colorful.Color) GradientTable {
+	numStripes := len(stripeColours)
+	table := make(GradientTable, 0, numStripes)
+	increment := 1.0 / float64(numStripes)
+	for i := 0; i < numStripes; i++ {
+		h, c, _ := stripeColours[i].Hcl()
+		table = append(table, GradientTable{{h, c, float64(i) * increment}}...)
+		table = append(table, GradientTable{{h, c, float64(i+1) * increment}}...)
+	}
+
+	return table
+}
+
+// withSaturation creates a copy of the GradientTable with the same saturation at every point.
+func (g GradientTable) withSaturation(saturation float64) GradientTable {
+	adjusted := make(GradientTable, len(g))
+	copy(adjusted, g)
+	for i := 0; i < len(adjusted); i++ {
+		adjusted[i].Saturation = saturation
+	}
+
+	return adjusted
+}
+
 // GetColor gets a colour at the specified point on the look-up table.
 func (g GradientTable) GetColor(t float64, l float64) colorful.Color {
 	for i := 0; i < len(g)-1; i++ {
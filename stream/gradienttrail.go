@@ -2,10 +2,134 @@ package stream
 
 import (
 	"math"
+	"math/rand"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/matt-g-everett/ledtx/util"
 )
 
+func init() {
+	trailLengthSpec := ParamSpec{Name: "trailLength", Type: ParamInt, Min: 0, Max: 10000,
+		Description: "Number of pixels before the gradient repeats, 0 uses the number of pixels"}
+
+	RegisterAnimation(AnimationType{
+		Name:        "gradient",
+		Description: "Moves a gradient along the strip, made from stripes of colour or a named gradient",
+		Params: []ParamSpec{
+			{Name: "colours", Type: ParamColours, Min: 1, Max: 16,
+				Description: "Colours of the stripes, replaces the named gradient"},
+			{Name: "gradient", Type: ParamString, Options: []string{"rainbow", "rainbowstep"},
+				Description: "Named gradient to use when there are no colours, defaults to rainbow"},
+			withDefault(trailLengthSpec, 310),
+			{Name: "speed", Type: ParamFloat, Min: -10, Max: 10, Description: "Pixels per millisecond"},
+			{Name: "luminance", Type: ParamFloat, Min: 0, Max: 1, Default: 0.06, Description: "Brightness"},
+		},
+		New: newGradientFromParams,
+	})
+
+	RegisterAnimation(AnimationType{
+		Name:        "rainbow",
+		Description: "Moves a rainbow along the strip",
+		Params: []ParamSpec{
+			trailLengthSpec,
+			{Name: "speed", Type: ParamFloat, Min: -10, Max: 10, Description: "Pixels per millisecond"},
+			{Name: "saturation", Type: ParamFloat, Min: 0, Max: 1, Description: "Saturation of every colour"},
+		},
+		New: newRainbowFromParams,
+	})
+
+	RegisterAnimation(AnimationType{
+		Name:        "stripes",
+		Description: "Moves stripes of random colours along the strip, sometimes with a white stripe",
+		Params: []ParamSpec{
+			{Name: "colours", Type: ParamColours, Min: 1, Max: 16, Description: "Colours of the stripes"},
+			{Name: "count", Type: ParamInt, Min: 1, Max: 16, Description: "Number of random colours"},
+			trailLengthSpec,
+			{Name: "speed", Type: ParamFloat, Min: -10, Max: 10, Description: "Pixels per millisecond"},
+			{Name: "luminance", Type: ParamFloat, Min: 0, Max: 1, Default: 0.2, Description: "Brightness"},
+		},
+		New: newStripesFromParams,
+	})
+}
+
+func withDefault(spec ParamSpec, def interface{}) ParamSpec {
+	spec.Default = def
+	return spec
+}
+
+// randomSpeed gets a speed between low and high in a random direction.
+func randomSpeed(r *rand.Rand, low float64, high float64) float64 {
+	speed := (r.Float64() * (high - low)) + low
+	sign := r.Float64() - 0.5
+	if sign > 0 {
+		return speed
+	} else {
+		return speed * -1.0
+	}
+}
+
+func trailLengthParam(ctx *AnimationContext, params Params, def int) uint32 {
+	trailLength := params.Int("trailLength", def)
+	if trailLength == 0 {
+		trailLength = ctx.Layout.Pixels
+	}
+
+	return uint32(trailLength)
+}
+
+func newGradientFromParams(ctx *AnimationContext, params Params) (Animation, error) {
+	var gradient GradientTable
+	if colours, ok := params["colours"].([]colorful.Color); ok {
+		gradient = NewStripeGradient(colours)
+	} else if params.Option("gradient", "rainbow") == "rainbowstep" {
+		gradient = rainbowStepGradient
+	} else {
+		gradient = rainbowGradient
+	}
+
+	trailLength := trailLengthParam(ctx, params, 310)
+	speed := params.Float("speed", randomSpeed(ctx.Rand, 0.2, 0.5))
+	return NewGradientTrail(ctx.Layout, gradient, trailLength, params.Float("luminance", 0.06), ctx.RuntimeMs, speed), nil
+}
+
+func newRainbowFromParams(ctx *AnimationContext, params Params) (Animation, error) {
+	trailLength := trailLengthParam(ctx, params, int(ctx.Rand.Int31n(900)+100))
+	speedMin := float64(trailLength) * 0.0004
+	speedMax := float64(trailLength) * 0.0006
+	speed := params.Float("speed", randomSpeed(ctx.Rand, speedMin, speedMax))
+
+	gradient := rainbowGradient.withSaturation(params.Float("saturation", util.RandomiseSaturation(ctx.Rand, 0.2, 0.8)))
+	return NewGradientTrail(ctx.Layout, gradient, trailLength, 0.06, ctx.RuntimeMs, speed), nil
+}
+
+func newStripesFromParams(ctx *AnimationContext, params Params) (Animation, error) {
+	stripeColours, ok := params["colours"].([]colorful.Color)
+	if !ok {
+		numColours := params.Int("count", ctx.Rand.Intn(4)+2)
+
+		// 30% chance that one of the colours is white
+		whiteIndex := ctx.Rand.Intn(numColours * 3)
+		stripeColours = make([]colorful.Color, numColours)
+		for i := 0; i < numColours; i++ {
+			if i == whiteIndex {
+				stripeColours[i] = colorful.Hsl(0.0, 0.0, 0.4)
+			} else {
+				saturation := util.RandomiseSaturation(ctx.Rand, ctx.SaturationMin, ctx.SaturationMax)
+				stripeColours[i] = colorful.Hsl(ctx.Rand.Float64()*360.0, saturation, 0.5)
+			}
+		}
+		params["colours"] = stripeColours
+	}
+
+	trailLength := trailLengthParam(ctx, params, int(ctx.Rand.Int31n(200)+200))
+	speed := params.Float("speed", randomSpeed(ctx.Rand, 0.3, 0.4))
+	stripeTable := NewStripeGradient(stripeColours)
+	return NewGradientTrail(ctx.Layout, stripeTable, trailLength, params.Float("luminance", 0.2), ctx.RuntimeMs, speed), nil
+}
+
 // A GradientTrail is an Animation that cycles a gradient along an led strip.
 type GradientTrail struct {
+	layout      *Layout
 	gradient    GradientTable
 	current     float64
 	trailLength uint32
@@ -16,10 +140,11 @@ type GradientTrail struct {
 }
 
 // NewGradientTrail creates an instance of a GradientTrail object.
-func NewGradientTrail(gradient GradientTable, trailLength uint32,
+func NewGradientTrail(layout *Layout, gradient GradientTable, trailLength uint32,
 	luminance float64, startTimeMs int64, pixelsPerMs float64) *GradientTrail {
 
 	g := new(GradientTrail)
+	g.layout = layout
 	g.gradient = gradient
 	g.trailLength = trailLength
 	g.luminance = luminance
@@ -31,11 +156,9 @@ func NewGradientTrail(gradient GradientTable, trailLength uint32,
 	return g
 }
 
-// CalculateFrame creates a new Frame instance.
-func (g *GradientTrail) CalculateFrame(runtimeMs int64) *Frame {
+func (g *GradientTrail) calculateSegment(pixels []colorful.Color) {
 	adjustmentFactor := 1.0
-	f := NewFrame()
-	numPixels := len(f.pixels)
+	numPixels := len(pixels)
 	for i := 0; i < numPixels; i++ {
 		if g.adjusted {
 			adjustmentFactor = 1.0 + 1.4*(float64(numPixels-i)/float64(numPixels))
@@ -44,7 +167,15 @@ func (g *GradientTrail) CalculateFrame(runtimeMs int64) *Frame {
 		adjustedTrailLength := float64(g.trailLength) * adjustmentFactor
 		t := math.Mod(float64(i)+(adjustmentFactor*g.current), float64(adjustedTrailLength)) / float64(adjustedTrailLength)
 		c := g.gradient.GetColor(t, g.luminance)
-		f.pixels[i] = c
+		pixels[i] = c
+	}
+}
+
+// CalculateFrame creates a new Frame instance.
+func (g *GradientTrail) CalculateFrame(runtimeMs int64) *Frame {
+	f := NewFrame(g.layout)
+	for _, s := range f.Segments() {
+		g.calculateSegment(f.SegmentPixels(s))
 	}
 
 	intervalMs := runtimeMs - g.runtimeMs
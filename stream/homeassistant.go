@@ -0,0 +1,257 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+const (
+	// AvailabilityOnline is published on the availability topic after connecting.
+	AvailabilityOnline = "online"
+	// AvailabilityOffline is the MQTT will that the broker publishes when ledtx disconnects.
+	AvailabilityOffline = "offline"
+
+	defaultDiscoveryPrefix = "homeassistant"
+	defaultObjectID        = "ledtx"
+	solidAnimation         = "solid"
+	playlistEffect         = "playlist"
+)
+
+// haColour is an RGB colour in Home Assistant's JSON schema, each channel is 0-255.
+type haColour struct {
+	R int `json:"r"`
+	G int `json:"g"`
+	B int `json:"b"`
+}
+
+// haLightCommand is a command from Home Assistant's MQTT JSON schema light.
+type haLightCommand struct {
+	State      string    `json:"state"`
+	Brightness *int      `json:"brightness"`
+	Color      *haColour `json:"color"`
+	Effect     string    `json:"effect"`
+}
+
+// haLightState is the state of the light in Home Assistant's MQTT JSON schema.
+type haLightState struct {
+	State      string    `json:"state"`
+	Brightness int       `json:"brightness"`
+	ColorMode  string    `json:"color_mode"`
+	Color      *haColour `json:"color,omitempty"`
+	Effect     string    `json:"effect,omitempty"`
+}
+
+// haLightDiscovery is the discovery config for an MQTT JSON schema light.
+type haLightDiscovery struct {
+	Name                string   `json:"name"`
+	UniqueID            string   `json:"unique_id"`
+	Schema              string   `json:"schema"`
+	CommandTopic        string   `json:"command_topic"`
+	StateTopic          string   `json:"state_topic"`
+	AvailabilityTopic   string   `json:"availability_topic,omitempty"`
+	PayloadAvailable    string   `json:"payload_available,omitempty"`
+	PayloadNotAvailable string   `json:"payload_not_available,omitempty"`
+	Brightness          bool     `json:"brightness"`
+	SupportedColorModes []string `json:"supported_color_modes"`
+	Effect              bool     `json:"effect"`
+	EffectList          []string `json:"effect_list"`
+}
+
+// HomeAssistant makes the Controller appear as a light in Home Assistant using MQTT discovery. The animation
+// types and presets are the light's effects and a colour shows a solid fill, both stay on until the playlist
+// effect resumes cycling.
+type HomeAssistant struct {
+	config      Config
+	client      mqtt.Client
+	controller  *Controller
+	objectID    string
+	configTopic string
+	command     string
+	state       string
+}
+
+// NewHomeAssistant creates an instance of a HomeAssistant.
+func NewHomeAssistant(config Config, client mqtt.Client, controller *Controller) *HomeAssistant {
+	h := new(HomeAssistant)
+	h.config = config
+	h.client = client
+	h.controller = controller
+
+	prefix := config.HomeAssistant.DiscoveryPrefix
+	if prefix == "" {
+		prefix = defaultDiscoveryPrefix
+	}
+
+	h.objectID = config.HomeAssistant.ObjectID
+	if h.objectID == "" {
+		h.objectID = defaultObjectID
+	}
+
+	base := fmt.Sprintf("%s/light/%s", prefix, h.objectID)
+	h.configTopic = base + "/config"
+	h.command = config.HomeAssistant.Command
+	if h.command == "" {
+		h.command = base + "/set"
+	}
+	h.state = config.HomeAssistant.State
+	if h.state == "" {
+		h.state = base + "/state"
+	}
+
+	return h
+}
+
+func (h *HomeAssistant) effects() []string {
+	effects := []string{playlistEffect}
+	for _, t := range AnimationTypes() {
+		if t.Name != solidAnimation {
+			effects = append(effects, t.Name)
+		}
+	}
+
+	return append(effects, AnimationPresets()...)
+}
+
+// PublishDiscovery publishes the retained config that makes the light appear in Home Assistant.
+func (h *HomeAssistant) PublishDiscovery() {
+	name := h.config.HomeAssistant.Name
+	if name == "" {
+		name = h.objectID
+	}
+
+	discovery := haLightDiscovery{
+		Name:                name,
+		UniqueID:            h.objectID,
+		Schema:              "json",
+		CommandTopic:        h.command,
+		StateTopic:          h.state,
+		Brightness:          true,
+		SupportedColorModes: []string{"rgb"},
+		Effect:              true,
+		EffectList:          h.effects(),
+	}
+
+	if h.config.Mqtt.Topics.Availability != "" {
+		discovery.AvailabilityTopic = h.config.Mqtt.Topics.Availability
+		discovery.PayloadAvailable = AvailabilityOnline
+		discovery.PayloadNotAvailable = AvailabilityOffline
+	}
+
+	h.publish(h.configTopic, discovery)
+}
+
+// PublishState publishes the Controller's status as the light's retained state.
+func (h *HomeAssistant) PublishState() {
+	status := h.controller.Status()
+	state := haLightState{
+		State:      "OFF",
+		Brightness: int(math.Round(status.Brightness * 255.0)),
+		ColorMode:  "rgb",
+	}
+
+	if status.Power {
+		state.State = "ON"
+	}
+
+	if status.Current.Animation == solidAnimation {
+		if colour, ok := status.Current.Params["colour"].(colorful.Color); ok {
+			r, g, b := colour.Clamped().RGB255()
+			state.Color = &haColour{R: int(r), G: int(g), B: int(b)}
+		}
+	} else if status.Cycling {
+		state.Effect = playlistEffect
+	} else {
+		// The entry's name can be anything, Home Assistant only knows the types and presets in the effect list
+		state.Effect = status.Current.Animation
+	}
+
+	h.publish(h.state, state)
+}
+
+func (h *HomeAssistant) publish(topic string, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Failed to encode message for %s. %s", topic, err)
+		return
+	}
+
+	token := h.client.Publish(topic, 0, true, b)
+	token.Wait()
+	if token.Error() != nil {
+		log.Printf("Failed to publish to %s. %s", topic, token.Error())
+	}
+}
+
+func (h *HomeAssistant) run(command haLightCommand) error {
+	if command.State == "OFF" {
+		h.controller.SetPower(false)
+		return nil
+	}
+
+	if command.Brightness != nil {
+		if err := h.controller.SetBrightness(float64(*command.Brightness) / 255.0); err != nil {
+			return err
+		}
+	}
+
+	if command.Color != nil {
+		colour := colorful.Color{
+			R: float64(command.Color.R) / 255.0,
+			G: float64(command.Color.G) / 255.0,
+			B: float64(command.Color.B) / 255.0,
+		}
+		if err := h.hold(solidAnimation, Params{"colour": colour}); err != nil {
+			return err
+		}
+	} else if command.Effect == playlistEffect {
+		if err := h.controller.SetCycling(true); err != nil {
+			return err
+		}
+	} else if command.Effect != "" {
+		if err := h.hold(command.Effect, nil); err != nil {
+			return err
+		}
+	}
+
+	if command.State == "ON" {
+		h.controller.SetPower(true)
+	}
+
+	return nil
+}
+
+// hold plays an animation and stops the playlist from moving on.
+func (h *HomeAssistant) hold(name string, params Params) error {
+	if err := h.controller.Play(name, params); err != nil {
+		return err
+	}
+
+	return h.controller.SetCycling(false)
+}
+
+func (h *HomeAssistant) handleCommandMessages(client mqtt.Client, msg mqtt.Message) {
+	var command haLightCommand
+	if err := json.Unmarshal(msg.Payload(), &command); err != nil {
+		log.Printf("Failed to decode Home Assistant command. %s", err)
+		return
+	}
+
+	if err := h.run(command); err != nil {
+		log.Printf("Failed to run Home Assistant command. %s", err)
+	}
+}
+
+// Subscribe to listen for commands from Home Assistant and announce the light.
+func (h *HomeAssistant) Subscribe() {
+	if token := h.client.Subscribe(h.command, 0, h.handleCommandMessages); token.Wait() && token.Error() != nil {
+		log.Printf("Failed to subscribe to Home Assistant commands. %s", token.Error())
+	}
+
+	h.PublishDiscovery()
+	h.PublishState()
+}
@@ -1,9 +1,34 @@
 package stream
 
-import "github.com/matt-g-everett/ledtx/stream/stripe"
+import (
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/matt-g-everett/ledtx/stream/stripe"
+)
+
+func init() {
+	RegisterAnimation(AnimationType{
+		Name:        "istripe",
+		Description: "Stripes of random lengths that never repeat, with colours from a palette or chosen at random",
+		Params: []ParamSpec{
+			{Name: "colours", Type: ParamColours, Min: 2, Max: 16, Description: "Palette of stripe colours"},
+			{Name: "speed", Type: ParamFloat, Min: -10, Max: 10, Description: "Pixels per millisecond"},
+		},
+		New: newInfinityStripeFromParams,
+	})
+}
+
+func newInfinityStripeFromParams(ctx *AnimationContext, params Params) (Animation, error) {
+	if palette, ok := params["colours"].([]colorful.Color); ok {
+		return NewInfinityStripe(ctx.Layout, ctx.RuntimeMs, params.Float("speed", 0.6), stripe.NewRandomStripeGenerator(ctx.Rand, palette)), nil
+	}
+
+	generator := stripe.NewRandomStripeGeneratorVariableSaturation(ctx.Rand, ctx.SaturationMin, ctx.SaturationMax)
+	return NewInfinityStripe(ctx.Layout, ctx.RuntimeMs, params.Float("speed", 0.5), generator), nil
+}
 
-// A GradientTrail is an Animation that cycles a gradient along an led strip.
+// An InfinityStripe is an Animation that moves stripes along an led strip.
 type InfinityStripe struct {
+	layout      *Layout
 	stripes     []stripe.Stripe
 	current     float64
 	runtimeMs   int64
@@ -13,9 +38,10 @@ type InfinityStripe struct {
 }
 
 // NewInfinityStripe creates an instance of a InfinityStripe object.
-func NewInfinityStripe(startTimeMs int64, pixelsPerMs float64, stripeGenerator stripe.StripeGenerator) *InfinityStripe {
+func NewInfinityStripe(layout *Layout, startTimeMs int64, pixelsPerMs float64, stripeGenerator stripe.StripeGenerator) *InfinityStripe {
 
 	s := new(InfinityStripe)
+	s.layout = layout
 	s.stripes = make([]stripe.Stripe, 0, 20)
 	s.runtimeMs = startTimeMs
 	s.pixelsPerMs = pixelsPerMs
@@ -59,10 +85,27 @@ func (s *InfinityStripe) getStripe(offset float64) (stripe.Stripe, float64) {
 	return lastStripe, float64(length)
 }
 
+func (s *InfinityStripe) calculateSegment(pixels []colorful.Color) {
+	adjustmentFactor := 1.0
+	numPixels := len(pixels)
+	currentStripe, stripeEnd := s.getStripe(s.current)
+	for i := 0; i < numPixels; i++ {
+		if s.adjusted {
+			adjustmentFactor = 1.0 + 3.0*(float64(i)/float64(numPixels))
+		}
+
+		adjustedOffset := (adjustmentFactor * float64(i)) + s.current
+		if adjustedOffset > stripeEnd {
+			currentStripe, stripeEnd = s.getStripe(adjustedOffset)
+		}
+
+		pixels[i] = currentStripe.Colour
+	}
+}
+
 // CalculateFrame creates a new Frame instance.
 func (s *InfinityStripe) CalculateFrame(runtimeMs int64) *Frame {
-	f := NewFrame()
-	numPixels := len(f.pixels)
+	f := NewFrame(s.layout)
 
 	// Cull stripes that have passed
 	// toRemove := 0
@@ -79,19 +122,8 @@ func (s *InfinityStripe) CalculateFrame(runtimeMs int64) *Frame {
 
 	//maxOffset := 1.0 + 3.0*(float64(numPixels-1)/float64(numPixels))
 
-	adjustmentFactor := 1.0
-	currentStripe, stripeEnd := s.getStripe(s.current)
-	for i := 0; i < numPixels; i++ {
-		if s.adjusted {
-			adjustmentFactor = 1.0 + 3.0*(float64(i)/float64(numPixels))
-		}
-
-		adjustedOffset := (adjustmentFactor * float64(i)) + s.current
-		if adjustedOffset > stripeEnd {
-			currentStripe, stripeEnd = s.getStripe(adjustedOffset)
-		}
-
-		f.pixels[i] = currentStripe.Colour
+	for _, segment := range f.Segments() {
+		s.calculateSegment(f.SegmentPixels(segment))
 	}
 
 	intervalMs := runtimeMs - s.runtimeMs
@@ -0,0 +1,151 @@
+package stream
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// outlierPitchFactor is how many times further than the typical LED pitch a resolved pixel can be from
+	// its neighbours along the string before it's treated as an outlier.
+	outlierPitchFactor float64 = 3.0
+
+	// unsupportedConfidence is the confidence of a resolved pixel that only some of its neighbours agree with.
+	unsupportedConfidence float64 = 0.75
+)
+
+// InterpolatePixels fills in the pixels that calibration couldn't resolve. Resolved pixels that are
+// implausibly far from their neighbours along the string are rejected as outliers, then every pixel without
+// a trusted location is placed on a straight line between the nearest trusted pixels either side of it. The
+// confidence falls the further a pixel is from a trusted one.
+func InterpolatePixels(pixels []Pixel, count int) []PixelPosition {
+	positions := make([]PixelPosition, count)
+	trusted := make([]int, 0, count)
+	for i := range positions {
+		if i < len(pixels) && pixels[i].Resolved {
+			positions[i] = PixelPosition{Location: pixels[i].Location, Resolved: true, Confidence: 1.0}
+			trusted = append(trusted, i)
+		}
+	}
+
+	trusted = rejectOutliers(positions, trusted)
+	if len(trusted) == 0 {
+		return positions
+	}
+
+	for i := range positions {
+		if positions[i].Resolved && !positions[i].Outlier {
+			continue
+		}
+
+		// Find the trusted pixels either side
+		n := sort.SearchInts(trusted, i)
+		before, after := -1, -1
+		if n > 0 {
+			before = trusted[n-1]
+		}
+		if n < len(trusted) {
+			after = trusted[n]
+		}
+
+		var location Point
+		var confidence float64
+		switch {
+		case before < 0:
+			location = positions[after].Location
+			confidence = 0.25 / float64(after-i)
+		case after < 0:
+			location = positions[before].Location
+			confidence = 0.25 / float64(i-before)
+		default:
+			t := float64(i-before) / float64(after-before)
+			a, b := positions[before].Location, positions[after].Location
+			location = Point{X: a.X + t*(b.X-a.X), Y: a.Y + t*(b.Y-a.Y)}
+			confidence = 0.5 / float64(minInt(i-before, after-i))
+		}
+
+		positions[i].Location = location
+		positions[i].Interpolated = true
+		positions[i].Confidence = confidence
+	}
+
+	return positions
+}
+
+// rejectOutliers marks the resolved pixels that are too far from both of their nearest resolved pixels along
+// the string and returns the pixels that are left. The pixels at the ends of the string are compared with the
+// two pixels on their only side, so a good end pixel isn't rejected because its neighbour is an outlier. Pixels
+// that only one neighbour agrees with are trusted less.
+func rejectOutliers(positions []PixelPosition, resolved []int) []int {
+	pitch := ledPitch(positions)
+	if pitch == 0.0 || len(resolved) < 3 {
+		return resolved
+	}
+
+	plausible := func(a int, b int) bool {
+		gap := math.Abs(float64(b - a))
+		return distance(positions[a].Location, positions[b].Location) <= outlierPitchFactor*pitch*gap
+	}
+
+	trusted := make([]int, 0, len(resolved))
+	for n, i := range resolved {
+		var neighbours [2]int
+		switch n {
+		case 0:
+			neighbours = [2]int{resolved[1], resolved[2]}
+		case len(resolved) - 1:
+			neighbours = [2]int{resolved[n-1], resolved[n-2]}
+		default:
+			neighbours = [2]int{resolved[n-1], resolved[n+1]}
+		}
+
+		agree := 0
+		for _, neighbour := range neighbours {
+			if plausible(neighbour, i) {
+				agree++
+			}
+		}
+
+		switch agree {
+		case 0:
+			positions[i].Outlier = true
+			continue
+		case 1:
+			positions[i].Confidence = unsupportedConfidence
+		}
+
+		trusted = append(trusted, i)
+	}
+
+	return trusted
+}
+
+// ledPitch estimates the distance between neighbouring LEDs, as seen by the camera, from the median distance
+// between resolved pixels that are next to each other on the string. It's 0 if there aren't any.
+func ledPitch(positions []PixelPosition) float64 {
+	distances := make([]float64, 0, len(positions))
+	for i := 1; i < len(positions); i++ {
+		if positions[i-1].Resolved && positions[i].Resolved {
+			distances = append(distances, distance(positions[i-1].Location, positions[i].Location))
+		}
+	}
+
+	if len(distances) == 0 {
+		return 0.0
+	}
+
+	sort.Float64s(distances)
+	return distances[len(distances)/2]
+}
+
+func distance(a Point, b Point) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
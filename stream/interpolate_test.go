@@ -0,0 +1,70 @@
+package stream
+
+import "testing"
+
+// straightString resolves pixels one unit apart along a line.
+func straightString(count int) []Pixel {
+	pixels := make([]Pixel, count)
+	for i := range pixels {
+		pixels[i] = Pixel{Resolved: true, Location: Point{X: float64(i), Y: 0}}
+	}
+
+	return pixels
+}
+
+func TestInterpolatePixelsRejectsOutliers(t *testing.T) {
+	pixels := straightString(12)
+	pixels[5].Location = Point{X: 50, Y: 50}
+	positions := InterpolatePixels(pixels, len(pixels))
+
+	if !positions[5].Outlier || !positions[5].Interpolated {
+		t.Fatalf("pixel 5 should be an interpolated outlier, got %+v", positions[5])
+	}
+	if positions[5].Location != (Point{X: 5, Y: 0}) {
+		t.Errorf("pixel 5 should be between its neighbours, got %v", positions[5].Location)
+	}
+
+	for _, i := range []int{4, 6} {
+		if positions[i].Outlier || positions[i].Confidence != unsupportedConfidence {
+			t.Errorf("pixel %d next to the outlier should be trusted less, got %+v", i, positions[i])
+		}
+	}
+}
+
+func TestInterpolatePixelsKeepsEndsNextToOutliers(t *testing.T) {
+	pixels := straightString(12)
+	pixels[1].Location = Point{X: 50, Y: 50}
+	pixels[10].Location = Point{X: -50, Y: 50}
+	positions := InterpolatePixels(pixels, len(pixels))
+
+	for _, i := range []int{1, 10} {
+		if !positions[i].Outlier {
+			t.Errorf("pixel %d should be an outlier, got %+v", i, positions[i])
+		}
+	}
+
+	for _, i := range []int{0, 11} {
+		if positions[i].Outlier || !positions[i].Resolved {
+			t.Errorf("end pixel %d should be kept, got %+v", i, positions[i])
+		}
+	}
+}
+
+func TestInterpolatePixelsFillsGaps(t *testing.T) {
+	pixels := straightString(10)
+	pixels[0].Resolved = false
+	pixels[4].Resolved = false
+	pixels[5].Resolved = false
+	positions := InterpolatePixels(pixels, 11)
+
+	for i, want := range map[int]Point{0: {X: 1}, 4: {X: 4}, 5: {X: 5}, 10: {X: 9}} {
+		if !positions[i].Interpolated || positions[i].Location != want {
+			t.Errorf("pixel %d should be interpolated at %v, got %+v", i, want, positions[i])
+		}
+	}
+
+	if positions[4].Confidence >= 1.0 || positions[10].Confidence >= positions[4].Confidence {
+		t.Errorf("confidence should fall away from resolved pixels, got %0.2f and %0.2f",
+			positions[4].Confidence, positions[10].Confidence)
+	}
+}
@@ -0,0 +1,91 @@
+package stream
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	defaultPixelCount  = 600
+	defaultSegmentName = "all"
+)
+
+// Segment is a named run of pixels within a Layout.
+type Segment struct {
+	Name   string `yaml:"name"`
+	Start  int    `yaml:"start"`
+	Length int    `yaml:"length"`
+}
+
+// End gets the index one past the last pixel in the Segment.
+func (s Segment) End() int {
+	return s.Start + s.Length
+}
+
+// Layout describes how many pixels are driven and how they are split into segments.
+type Layout struct {
+	Pixels   int       `yaml:"pixels"`
+	Segments []Segment `yaml:"segments"`
+}
+
+// NewLayout validates a configured Layout and fills in the defaults.
+func NewLayout(config Layout) (*Layout, error) {
+	l := new(Layout)
+	l.Pixels = config.Pixels
+	if l.Pixels == 0 {
+		l.Pixels = defaultPixelCount
+	}
+
+	// The pixel count is sent to the ledrx device as a uint16
+	if l.Pixels < 0 || l.Pixels > math.MaxUint16 {
+		return nil, fmt.Errorf("pixel count %d is out of range", l.Pixels)
+	}
+
+	if len(config.Segments) == 0 {
+		l.Segments = []Segment{{Name: defaultSegmentName, Start: 0, Length: l.Pixels}}
+		return l, nil
+	}
+
+	l.Segments = make([]Segment, len(config.Segments))
+	copy(l.Segments, config.Segments)
+
+	names := make(map[string]bool, len(l.Segments))
+	for _, s := range l.Segments {
+		if names[s.Name] {
+			return nil, fmt.Errorf("segment name %q is used more than once", s.Name)
+		}
+		names[s.Name] = true
+
+		if s.Length <= 0 {
+			return nil, fmt.Errorf("segment %q must have a positive length", s.Name)
+		}
+
+		if s.Start < 0 || s.End() > l.Pixels {
+			return nil, fmt.Errorf("segment %q (%d-%d) is outside of the %d pixels", s.Name, s.Start, s.End()-1, l.Pixels)
+		}
+	}
+
+	// Check for overlaps by looking at segments in pixel order
+	ordered := make([]Segment, len(l.Segments))
+	copy(ordered, l.Segments)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Start < ordered[j].Start })
+	for i := 1; i < len(ordered); i++ {
+		if ordered[i].Start < ordered[i-1].End() {
+			return nil, fmt.Errorf("segments %q and %q overlap", ordered[i-1].Name, ordered[i].Name)
+		}
+	}
+
+	return l, nil
+}
+
+// Segment finds a Segment by name.
+func (l *Layout) Segment(name string) (Segment, bool) {
+	for _, s := range l.Segments {
+		if s.Name == name {
+			return s, true
+		}
+	}
+
+	return Segment{}, false
+}
@@ -0,0 +1,66 @@
+package stream
+
+import (
+	"sync"
+)
+
+// MemoryTransport keeps every frame it's sent in memory, which is useful for tests.
+type MemoryTransport struct {
+	frames     [][]byte
+	ackHandler AckHandler
+	lock       sync.Mutex
+}
+
+// NewMemoryTransport creates an instance of a MemoryTransport.
+func NewMemoryTransport() *MemoryTransport {
+	t := new(MemoryTransport)
+	t.frames = make([][]byte, 0)
+	return t
+}
+
+// Send stores the binary form of a frame.
+func (t *MemoryTransport) Send(f *Frame) error {
+	b, err := f.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.frames = append(t.frames, b)
+	return nil
+}
+
+// SetAckHandler sets the function that's called by Ack.
+func (t *MemoryTransport) SetAckHandler(handler AckHandler) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.ackHandler = handler
+}
+
+// Ack simulates the device acknowledging a frame.
+func (t *MemoryTransport) Ack(ackID uint8) {
+	t.lock.Lock()
+	handler := t.ackHandler
+	t.lock.Unlock()
+
+	if handler != nil {
+		handler(ackID)
+	}
+}
+
+// Frames gets a copy of the list of frames that have been sent.
+func (t *MemoryTransport) Frames() [][]byte {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	frames := make([][]byte, len(t.frames))
+	copy(frames, t.frames)
+	return frames
+}
+
+// Reset discards the frames that have been sent.
+func (t *MemoryTransport) Reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.frames = t.frames[:0]
+}
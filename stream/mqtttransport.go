@@ -0,0 +1,65 @@
+package stream
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MqttTransport publishes frames to an ledrx device over MQTT.
+type MqttTransport struct {
+	config     Config
+	client     mqtt.Client
+	ackHandler AckHandler
+}
+
+// NewMqttTransport creates an instance of an MqttTransport.
+func NewMqttTransport(config Config, client mqtt.Client) *MqttTransport {
+	t := new(MqttTransport)
+	t.config = config
+	t.client = client
+	return t
+}
+
+// Send publishes a frame on the stream topic.
+func (t *MqttTransport) Send(f *Frame) error {
+	b, err := f.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	token := t.client.Publish(t.config.Mqtt.Topics.Stream, 0, false, b)
+	token.Wait()
+	return token.Error()
+}
+
+// SetAckHandler sets the function that's called for each ACK received from the device.
+func (t *MqttTransport) SetAckHandler(handler AckHandler) {
+	t.ackHandler = handler
+}
+
+func (t *MqttTransport) handleAckMessages(client mqtt.Client, msg mqtt.Message) {
+	var message AckMessage
+	if err := json.Unmarshal(msg.Payload(), &message); err != nil {
+		log.Printf("Failed to decode ACK message. %s", err)
+		return
+	}
+
+	if message.Type == "ack" {
+		if t.ackHandler != nil {
+			t.ackHandler(message.AckID)
+		}
+	} else {
+		log.Printf("Unrecognised message type %s on ack queue.", message.Type)
+	}
+}
+
+// Subscribe to listen for ACKs from the device.
+func (t *MqttTransport) Subscribe() {
+	if token := t.client.Subscribe(t.config.Mqtt.Topics.Ack, 0, t.handleAckMessages); token.Wait() && token.Error() != nil {
+		log.Println(token.Error())
+		os.Exit(1)
+	}
+}
@@ -7,6 +7,38 @@ import (
 	"github.com/matt-g-everett/ledtx/util"
 )
 
+func init() {
+	RegisterAnimation(AnimationType{
+		Name:        "twinkle",
+		Description: "Pixels twinkle at random, changing to another of the background colours as they fade",
+		Params: []ParamSpec{
+			{Name: "colours", Type: ParamColours, Min: 1, Max: 16, Description: "Background colours"},
+			{Name: "count", Type: ParamInt, Min: 1, Max: 16, Description: "Number of random background colours"},
+			{Name: "chance", Type: ParamInt, Min: 1, Max: 10000,
+				Description: "Each pixel has a 1 in chance of twinkling on every frame"},
+			{Name: "chanceRange", Type: ParamInt, Min: 1, Max: 10000, Default: 30,
+				Description: "When there's no chance, it's chosen at random from 20 up to 20 + chanceRange"},
+		},
+		New: newMultiTwinkleFromParams,
+	})
+}
+
+func newMultiTwinkleFromParams(ctx *AnimationContext, params Params) (Animation, error) {
+	backColours, ok := params["colours"].([]colorful.Color)
+	if !ok {
+		numColours := params.Int("count", ctx.Rand.Intn(8)+2)
+		backColours = make([]colorful.Color, numColours)
+		for i := 0; i < numColours; i++ {
+			saturation := util.RandomiseSaturation(ctx.Rand, ctx.SaturationMin, ctx.SaturationMax)
+			backColours[i] = colorful.Hsl(ctx.Rand.Float64()*360.0, saturation, 0.02)
+		}
+		params["colours"] = backColours
+	}
+
+	chance := params.Int("chance", int(ctx.Rand.Int31n(int32(params.Int("chanceRange", 30)))+20))
+	return NewMultiTwinkle(ctx.Layout, ctx.Rand, int32(chance), backColours, nil, ctx.RuntimeMs), nil
+}
+
 type multiParticle struct {
 	staticLut  []float64
 	lut        []float64
@@ -15,11 +47,13 @@ type multiParticle struct {
 	running    bool
 	colour     colorful.Color
 	NextColour colorful.Color
+	rand       *rand.Rand
 }
 
-func newMultiParticle(colour colorful.Color, lut []float64, memoizer util.Memoizer) *multiParticle {
+func newMultiParticle(r *rand.Rand, colour colorful.Color, lut []float64, memoizer util.Memoizer) *multiParticle {
 	p := new(multiParticle)
 
+	p.rand = r
 	p.colour = colour
 	p.NextColour = colour
 	p.staticLut = lut
@@ -35,7 +69,7 @@ func newMultiParticle(colour colorful.Color, lut []float64, memoizer util.Memoiz
 
 func (p *multiParticle) updateLut() {
 	if p.staticLut == nil {
-		p.lut = util.GenerateLutMemoized((rand.Intn(18)+6)*2, p.memoizer)
+		p.lut = util.GenerateLutMemoized((p.rand.Intn(18)+6)*2, p.memoizer)
 	}
 }
 
@@ -78,18 +112,22 @@ func (p *multiParticle) currentColour() colorful.Color {
 
 // A MultiTwinkle is an Animation that twinkles random particles.
 type MultiTwinkle struct {
+	layout              *Layout
 	lut                 []float64
 	backColours         []colorful.Color
 	runtimeMs           int64
 	scintillationChance int32
 	pixels              []*multiParticle
 	memoizer            util.Memoizer
+	rand                *rand.Rand
 }
 
 // NewMultiTwinkle creates an instance of a Twinkle object.
-func NewMultiTwinkle(scintillationChance int32, backColours []colorful.Color, lut []float64, runtimeMs int64) *MultiTwinkle {
+func NewMultiTwinkle(layout *Layout, r *rand.Rand, scintillationChance int32, backColours []colorful.Color, lut []float64, runtimeMs int64) *MultiTwinkle {
 	t := new(MultiTwinkle)
 
+	t.layout = layout
+	t.rand = r
 	t.lut = lut
 	t.backColours = backColours
 	t.scintillationChance = scintillationChance
@@ -100,27 +138,27 @@ func NewMultiTwinkle(scintillationChance int32, backColours []colorful.Color, lu
 }
 
 func (t *MultiTwinkle) getRandomBackColour() colorful.Color {
-	return t.backColours[rand.Int31n(int32(len(t.backColours)))]
+	return t.backColours[t.rand.Int31n(int32(len(t.backColours)))]
 }
 
 // CalculateFrame creates a new Frame instance.
 func (t *MultiTwinkle) CalculateFrame(runtimeMs int64) *Frame {
 	t.runtimeMs = runtimeMs
 
-	f := NewFrame()
-	numPixels := len(f.pixels)
+	f := NewFrame(t.layout)
+	numPixels := f.Len()
 
 	// Initialise if we need to
 	if t.pixels == nil {
 		t.pixels = make([]*multiParticle, numPixels)
 		for i := 0; i < numPixels; i++ {
-			t.pixels[i] = newMultiParticle(t.getRandomBackColour(), t.lut, t.memoizer)
+			t.pixels[i] = newMultiParticle(t.rand, t.getRandomBackColour(), t.lut, t.memoizer)
 		}
 	}
 
 	for i := 0; i < numPixels; i++ {
 		// Start scintillation by chance
-		if rand.Int31n(t.scintillationChance) == 0 {
+		if t.rand.Int31n(t.scintillationChance) == 0 {
 			if t.pixels[i].scintillate() {
 				t.pixels[i].NextColour = t.getRandomBackColour()
 			}
@@ -0,0 +1,159 @@
+package stream
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// Params holds the parameters for an animation by name. Once they've been validated against an AnimationType
+// the values are ints, float64s, strings, colorful.Colors or []colorful.Colors.
+type Params map[string]interface{}
+
+// Int gets an integer parameter, or def if it's not set. The value that's used is stored in the Params so
+// that they describe the animation that was created.
+func (p Params) Int(name string, def int) int {
+	if v, ok := p[name].(int); ok {
+		return v
+	}
+
+	p[name] = def
+	return def
+}
+
+// Float gets a floating point parameter, or def if it's not set. The value that's used is stored in the Params.
+func (p Params) Float(name string, def float64) float64 {
+	if v, ok := p[name].(float64); ok {
+		return v
+	}
+
+	p[name] = def
+	return def
+}
+
+// Option gets a string parameter, or def if it's not set. The value that's used is stored in the Params.
+func (p Params) Option(name string, def string) string {
+	if v, ok := p[name].(string); ok {
+		return v
+	}
+
+	p[name] = def
+	return def
+}
+
+// Colour gets a colour parameter, or def if it's not set. The value that's used is stored in the Params.
+func (p Params) Colour(name string, def colorful.Color) colorful.Color {
+	if v, ok := p[name].(colorful.Color); ok {
+		return v
+	}
+
+	p[name] = def
+	return def
+}
+
+// Colours gets a list of colours, or def if it's not set. The value that's used is stored in the Params.
+func (p Params) Colours(name string, def []colorful.Color) []colorful.Color {
+	if v, ok := p[name].([]colorful.Color); ok {
+		return v
+	}
+
+	p[name] = def
+	return def
+}
+
+// Describe formats the Params for logging, colours are written so they can be pasted into Go source.
+func (p Params) Describe() string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		switch v := p[name].(type) {
+		case colorful.Color:
+			parts = append(parts, fmt.Sprintf("%s: %s", name, SprintColour(v)))
+		case []colorful.Color:
+			parts = append(parts, fmt.Sprintf("%s: %s", name, SprintColours(v)))
+		case float64:
+			parts = append(parts, fmt.Sprintf("%s: %0.3f", name, v))
+		default:
+			parts = append(parts, fmt.Sprintf("%s: %v", name, v))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// parseColour reads a colour from a hex string, an {r, g, b} map or a colorful.Color.
+func parseColour(value interface{}) (colorful.Color, error) {
+	switch v := value.(type) {
+	case colorful.Color:
+		return v, nil
+	case string:
+		return colorful.Hex(v)
+	case map[string]interface{}:
+		return parseColourMap(func(k string) (interface{}, bool) {
+			for key, value := range v {
+				if strings.EqualFold(key, k) {
+					return value, true
+				}
+			}
+			return nil, false
+		})
+	case map[interface{}]interface{}:
+		return parseColourMap(func(k string) (interface{}, bool) {
+			for key, value := range v {
+				if s, ok := key.(string); ok && strings.EqualFold(s, k) {
+					return value, true
+				}
+			}
+			return nil, false
+		})
+	}
+
+	return colorful.Color{}, fmt.Errorf("colour %v should be a hex string or have r, g and b values", value)
+}
+
+func parseColourMap(lookup func(k string) (interface{}, bool)) (colorful.Color, error) {
+	var channels [3]float64
+	for i, k := range []string{"r", "g", "b"} {
+		value, ok := lookup(k)
+		if !ok {
+			return colorful.Color{}, fmt.Errorf("colour is missing a value for %s", k)
+		}
+
+		switch v := value.(type) {
+		case int:
+			channels[i] = float64(v)
+		case float64:
+			channels[i] = v
+		default:
+			return colorful.Color{}, fmt.Errorf("colour value %v for %s should be a number", value, k)
+		}
+	}
+
+	return colorful.Color{R: channels[0], G: channels[1], B: channels[2]}, nil
+}
+
+// SprintColour formats a colour as Go source.
+func SprintColour(colour colorful.Color) string {
+	return fmt.Sprintf("{R: %0.3f, G: %0.3f, B: %0.3f}", colour.R, colour.G, colour.B)
+}
+
+// SprintColours formats a list of colours as Go source.
+func SprintColours(colours []colorful.Color) string {
+	colourCode := "[]colorful.Color{"
+	for i := 0; i < len(colours); i++ {
+		colourCode += SprintColour(colours[i])
+		if i < len(colours)-1 {
+			colourCode += ", "
+		}
+	}
+	colourCode += "}"
+
+	return colourCode
+}
@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+)
+
+// PixelPosition is where a pixel is on the tree in camera co-ordinates. Pixels that calibration couldn't
+// find, or that were too far from their neighbours, are interpolated along the string. The confidence runs
+// from 0 for a guess to 1 for a pixel that was resolved and agrees with its neighbours.
+type PixelPosition struct {
+	Location     Point   `json:"loc"`
+	Resolved     bool    `json:"resolved"`
+	Interpolated bool    `json:"interpolated"`
+	Outlier      bool    `json:"outlier"`
+	Confidence   float64 `json:"confidence"`
+}
+
+// A PixelMap gives the position of every pixel in a Layout, so that animations can draw in space instead of
+// along the string.
+type PixelMap struct {
+	positions []PixelPosition
+	min       Point
+	max       Point
+}
+
+// LoadPixelMap reads the pixel locations that calibration stored. Calibrations from before the gaps were
+// filled in are interpolated as they're loaded.
+func LoadPixelMap(layout *Layout) (*PixelMap, error) {
+	var positions []PixelPosition
+	err := readJSON(InterpolatedPixelsPath, &positions)
+	if os.IsNotExist(err) {
+		var pixels []Pixel
+		if err := readJSON(ResolvedPixelsPath, &pixels); err != nil {
+			return nil, err
+		}
+		positions = InterpolatePixels(pixels, layout.Pixels)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return NewPixelMap(positions, layout)
+}
+
+// loadCalibratedPixelMap loads the pixel locations from the last calibration, it's nil if there aren't any.
+func loadCalibratedPixelMap(layout *Layout) *PixelMap {
+	m, err := LoadPixelMap(layout)
+	if err != nil {
+		log.Printf("Failed to load the pixel map, spatial animations will use pixel order. %s", err)
+		return nil
+	}
+
+	log.Printf("Loaded the pixel map, %d of %d pixels were resolved", m.Resolved(), m.Len())
+	return m
+}
+
+func readJSON(path string, v interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("failed to read %s. %s", path, err)
+	}
+
+	return nil
+}
+
+// NewPixelMap creates a PixelMap from interpolated positions, there must be one for every pixel and at least
+// one must have been resolved.
+func NewPixelMap(positions []PixelPosition, layout *Layout) (*PixelMap, error) {
+	if len(positions) != layout.Pixels {
+		return nil, fmt.Errorf("calibration has %d pixels but the layout has %d", len(positions), layout.Pixels)
+	}
+
+	m := new(PixelMap)
+	m.positions = positions
+	if m.Resolved() == 0 {
+		return nil, fmt.Errorf("none of the %d pixels have been resolved", layout.Pixels)
+	}
+
+	m.min = Point{X: math.Inf(1), Y: math.Inf(1)}
+	m.max = Point{X: math.Inf(-1), Y: math.Inf(-1)}
+	for _, p := range m.positions {
+		m.min.X = math.Min(m.min.X, p.Location.X)
+		m.min.Y = math.Min(m.min.Y, p.Location.Y)
+		m.max.X = math.Max(m.max.X, p.Location.X)
+		m.max.Y = math.Max(m.max.Y, p.Location.Y)
+	}
+
+	return m, nil
+}
+
+// Len gets the number of pixels in the map.
+func (m *PixelMap) Len() int {
+	return len(m.positions)
+}
+
+// Position gets the position of a pixel.
+func (m *PixelMap) Position(i int) PixelPosition {
+	return m.positions[i]
+}
+
+// Bounds gets the top left and bottom right corners of the box around every pixel.
+func (m *PixelMap) Bounds() (Point, Point) {
+	return m.min, m.max
+}
+
+// Normalised gets the position of a pixel scaled to the bounds, so that both co-ordinates run from 0 to 1.
+// Y is 0 at the top of the tree.
+func (m *PixelMap) Normalised(i int) Point {
+	p := m.positions[i].Location
+	return Point{X: normalise(p.X, m.min.X, m.max.X), Y: normalise(p.Y, m.min.Y, m.max.Y)}
+}
+
+// Resolved counts the pixels that calibration found and that agree with their neighbours.
+func (m *PixelMap) Resolved() int {
+	count := 0
+	for _, p := range m.positions {
+		if p.Resolved && !p.Outlier {
+			count++
+		}
+	}
+
+	return count
+}
+
+func normalise(v float64, min float64, max float64) float64 {
+	if max == min {
+		return 0.0
+	}
+
+	return (v - min) / (max - min)
+}
@@ -0,0 +1,195 @@
+package stream
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	defaultPlaylistName = "default"
+	defaultDuration     = 30 * time.Second
+	defaultTransition   = 5 * time.Second
+)
+
+// A PlaylistEntry describes an animation to show and for how long. The transition is how long it takes to
+// change to the animation, an explicit 0 switches straight away, and the style and easing are how it
+// changes. The seed fixes the random choices, otherwise they come from the show's seed and the entry's
+// position. An entry can recall a favourite instead of having an animation.
+type PlaylistEntry struct {
+	Name            string         `yaml:"name"`
+	Animation       string         `yaml:"animation"`
+	Favourite       string         `yaml:"favourite"`
+	Params          Params         `yaml:"params"`
+	Duration        time.Duration  `yaml:"duration"`
+	Transition      *time.Duration `yaml:"transition"`
+	TransitionStyle string         `yaml:"transitionStyle"`
+	Easing          string         `yaml:"easing"`
+	Seed            *int64         `yaml:"seed"`
+}
+
+// A Playlist is a list of animations that are shown in order, starting again at the end.
+type Playlist []PlaylistEntry
+
+// defaultPlaylistAnimations is used when the config doesn't have a default playlist.
+var defaultPlaylistAnimations = []string{
+	"multi:monokai",
+	//"streak:random",
+	"istripe:70s",
+	"istripe:random",
+	"multi:monokai",
+	"stripes:random",
+	"gradient:purplegoldblue",
+	"multi:purplegoldblue",
+	"istripe:random",
+	"multi:random",
+	"stripes:candycane",
+	"istripe:70s",
+	"gradient:pinkorangewhite",
+	"multi:random3",
+	"stripes:random",
+	"gradient:rainbowstep",
+	"multi:purplegoldblue",
+	"istripe:70s",
+	"rainbow:fixed",
+	"multi:monokai",
+	"stripes:candycane",
+	"twinkle:random",
+	"istripe:random",
+	"multi:redgreengold",
+	"twinkle:blue",
+	"stripes:random",
+	"multi:random",
+	"istripe:70s",
+	"multi:pinksilverblue",
+	"rainbow:random",
+	"multi:purplegoldblue",
+	"twinkle:random",
+	"istripe:random",
+	"multi:random3",
+	"stripes:candycane",
+	"multi:random2",
+	"istripe:70s",
+	"twinkle:pink",
+	"stripes:random",
+	"multi:redgreengold",
+	"multi:monokai",
+	"rainbow:normal",
+	"multi:random",
+	"istripe:random",
+	"multi:redwhiteblue",
+	"gradient:pinkorangewhite",
+	"twinkle:random",
+	"stripes:candycane",
+	"multi:redgreengold",
+	"istripe:70s",
+	"twinkle:gold",
+	"stripes:random",
+	"multi:random2",
+	"rainbow:random",
+	"multi:purplegoldblue",
+	"stripes:random",
+	"twinkle:random",
+	"istripe:70s",
+	"istripe:random",
+	"multi:random3",
+	"stripes:candycane",
+	"multi:random",
+	"multi:pinksilverblue",
+	"twinkle:silver",
+	"stripes:random",
+	"multi:redwhiteblue",
+	"istripe:70s",
+	"rainbow:random",
+	"multi:random3",
+}
+
+// NewPlaylists validates the configured playlists and fills in the defaults for each entry. A default
+// playlist is added if the config doesn't have one.
+func NewPlaylists(config Config, favourites *Favourites) (map[string]Playlist, error) {
+	playlists := make(map[string]Playlist, len(config.Playlists)+1)
+	for name, configured := range config.Playlists {
+		if len(configured) == 0 {
+			return nil, fmt.Errorf("playlist %q is empty", name)
+		}
+
+		playlist := make(Playlist, len(configured))
+		for i, entry := range configured {
+			if entry.Favourite != "" {
+				if entry.Animation != "" {
+					return nil, fmt.Errorf("entry %d in playlist %q has an animation and a favourite", i, name)
+				}
+
+				favourite, ok := favourites.Get(entry.Favourite)
+				if !ok {
+					return nil, fmt.Errorf("entry %d in playlist %q recalls an unknown favourite %q", i, name, entry.Favourite)
+				}
+
+				if entry.Name == "" {
+					entry.Name = entry.Favourite
+				}
+				recalled := favourite.entry(entry.Name)
+				entry.Animation = recalled.Animation
+				entry.Params = recalled.Params
+				entry.Seed = recalled.Seed
+			}
+
+			if entry.Animation == "" {
+				return nil, fmt.Errorf("entry %d in playlist %q doesn't have an animation", i, name)
+			}
+
+			if entry.Duration < 0 || (entry.Transition != nil && *entry.Transition < 0) {
+				return nil, fmt.Errorf("entry %d in playlist %q has a negative time", i, name)
+			}
+
+			if err := ValidateAnimation(entry.Animation, entry.Params); err != nil {
+				return nil, fmt.Errorf("entry %d in playlist %q is invalid. %s", i, name, err)
+			}
+
+			if err := ValidateTransition(entry.TransitionStyle, entry.Easing); err != nil {
+				return nil, fmt.Errorf("entry %d in playlist %q is invalid. %s", i, name, err)
+			}
+
+			playlist[i] = entry.withDefaults()
+		}
+		playlists[name] = playlist
+	}
+
+	if _, ok := playlists[defaultPlaylistName]; !ok {
+		playlist := make(Playlist, len(defaultPlaylistAnimations))
+		for i, animation := range defaultPlaylistAnimations {
+			playlist[i] = PlaylistEntry{Animation: animation}.withDefaults()
+		}
+		playlists[defaultPlaylistName] = playlist
+	}
+
+	return playlists, nil
+}
+
+func (e PlaylistEntry) withDefaults() PlaylistEntry {
+	if e.Name == "" {
+		e.Name = e.Animation
+	}
+
+	if e.Params == nil {
+		e.Params = Params{}
+	}
+
+	if e.Duration == 0 {
+		e.Duration = defaultDuration
+	}
+
+	if e.Transition == nil {
+		transition := defaultTransition
+		e.Transition = &transition
+	}
+
+	if e.TransitionStyle == "" {
+		e.TransitionStyle = defaultTransitionStyle
+	}
+
+	if e.Easing == "" {
+		e.Easing = defaultEasing
+	}
+
+	return e
+}
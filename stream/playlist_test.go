@@ -0,0 +1,73 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestPlaylistTransitions(t *testing.T) {
+	var config Config
+	err := yaml.Unmarshal([]byte(`
+playlists:
+  test:
+    - animation: solid
+    - animation: solid
+      transition: 0s
+    - animation: solid
+      transition: 2s
+`), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playlists, err := NewPlaylists(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []time.Duration{defaultTransition, 0, 2 * time.Second} {
+		if got := *playlists["test"][i].Transition; got != want {
+			t.Errorf("entry %d has a transition of %s, expected %s", i, got, want)
+		}
+	}
+}
+
+func TestPlaylistTransitionOfZeroCuts(t *testing.T) {
+	cut := time.Duration(0)
+	playlists := map[string]Playlist{
+		"test": {
+			{Animation: "solid", Params: Params{"colour": "#ff0000"}},
+			{Animation: "solid", Params: Params{"colour": "#0000ff"}, Transition: &cut},
+		},
+	}
+	c := newTestController(t, 10, 1, playlists, "test")
+	c.CalculateFrame(0)
+
+	if err := c.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	if f := c.CalculateFrame(40); f.pixels[0].R != 0 || f.pixels[0].B != 1 {
+		t.Errorf("the first frame after a cut should be blue, got %s", f.pixels[0].Hex())
+	}
+	if c.Status().Next != nil {
+		t.Error("there shouldn't be a transition in progress after a cut")
+	}
+}
+
+func TestShowEntryUsesTheDefaults(t *testing.T) {
+	playlists := map[string]Playlist{"test": {{Animation: "solid"}}}
+	c := newTestController(t, 10, 1, playlists, "test")
+	c.CalculateFrame(0)
+
+	// An entry that didn't come from a playlist doesn't have a transition
+	c.lock.Lock()
+	c.showEntry(PlaylistEntry{Animation: "solid"}, -1)
+	c.lock.Unlock()
+
+	if c.transitionTimeSecs != defaultTransition.Seconds() {
+		t.Errorf("the transition takes %0.1fs, expected the default of %s", c.transitionTimeSecs, defaultTransition)
+	}
+}
@@ -0,0 +1,122 @@
+package stream
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMilliampsPerChannel = 20.0
+	defaultVolts               = 5.0
+	defaultPowerReportInterval = 60 * time.Second
+)
+
+// PowerLimiter estimates the current drawn by a frame from its device values and scales the frame down when
+// the estimate exceeds the power supply's budget.
+type PowerLimiter struct {
+	milliampsPerChannel float64
+	idleMilliamps       float64
+	budgetMilliamps     float64
+	volts               float64
+	reportInterval      time.Duration
+	lastReport          time.Time
+	limitedFrames       int
+	watts               float64
+	lock                sync.Mutex
+}
+
+// NewPowerLimiter creates an instance of a PowerLimiter from the power config. A supply of zero means that
+// frames are never limited, the estimate is still reported.
+func NewPowerLimiter(config Config, layout *Layout) (*PowerLimiter, error) {
+	power := config.Power
+	if power.HeadroomPercent < 0 || power.HeadroomPercent >= 100 {
+		return nil, fmt.Errorf("power headroom %0.1f%% should be at least 0 and less than 100", power.HeadroomPercent)
+	}
+
+	p := new(PowerLimiter)
+	p.milliampsPerChannel = power.MilliampsPerChannel
+	if p.milliampsPerChannel == 0 {
+		p.milliampsPerChannel = defaultMilliampsPerChannel
+	}
+
+	p.volts = power.Volts
+	if p.volts == 0 {
+		p.volts = defaultVolts
+	}
+
+	p.idleMilliamps = power.IdleMilliampsPerPixel * float64(layout.Pixels)
+	p.budgetMilliamps = power.SupplyMilliamps * power.Headroom()
+
+	p.reportInterval = power.ReportInterval
+	if p.reportInterval == 0 {
+		p.reportInterval = defaultPowerReportInterval
+	}
+	p.lastReport = time.Now()
+
+	return p, nil
+}
+
+// Estimate gets the current in mA that a set of device values will draw.
+func (p *PowerLimiter) Estimate(rgb []byte) float64 {
+	var total int
+	for _, v := range rgb {
+		total += int(v)
+	}
+
+	return p.idleMilliamps + (float64(total)/255.0)*p.milliampsPerChannel
+}
+
+func (p *PowerLimiter) estimateLevels(levels []float64) float64 {
+	var total float64
+	for _, v := range levels {
+		total += v
+	}
+
+	return p.idleMilliamps + (total/255.0)*p.milliampsPerChannel
+}
+
+// Apply scales the corrected device levels of a frame in place so that it stays within the budget. It runs
+// before the levels are quantised, rounding and dithering can add up to a step to each channel so the budget
+// leaves room for that.
+func (p *PowerLimiter) Apply(levels []float64) {
+	milliamps := p.estimateLevels(levels)
+
+	limited := false
+	if p.budgetMilliamps > 0 && milliamps > p.budgetMilliamps {
+		// Only the LEDs can be dimmed, the idle current is fixed
+		rounding := float64(len(levels)) * p.milliampsPerChannel / 255.0
+		scale := (p.budgetMilliamps - p.idleMilliamps - rounding) / (milliamps - p.idleMilliamps)
+		if scale < 0 {
+			scale = 0
+		}
+
+		for i := range levels {
+			levels[i] *= scale
+		}
+		milliamps = p.estimateLevels(levels)
+		limited = true
+	}
+
+	p.lock.Lock()
+	p.watts = (milliamps / 1000.0) * p.volts
+	if limited {
+		p.limitedFrames++
+	}
+
+	if time.Since(p.lastReport) >= p.reportInterval {
+		log.Printf("Power: %0.1f W (%0.0f mA), %d frame(s) limited since the last report",
+			p.watts, milliamps, p.limitedFrames)
+		p.limitedFrames = 0
+		p.lastReport = time.Now()
+	}
+	p.lock.Unlock()
+}
+
+// Watts gets the estimated power drawn by the last frame.
+func (p *PowerLimiter) Watts() float64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.watts
+}
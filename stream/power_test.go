@@ -0,0 +1,84 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestPowerLimiterHeadroom(t *testing.T) {
+	layout := &Layout{Pixels: 10}
+	for _, test := range []struct {
+		headroom float64
+		valid    bool
+	}{
+		{headroom: 0, valid: true},
+		{headroom: 10, valid: true},
+		{headroom: 99.5, valid: true},
+		{headroom: -1, valid: false},
+		{headroom: 100, valid: false},
+		{headroom: 150, valid: false},
+	} {
+		config := Config{Power: PowerConfig{SupplyMilliamps: 1000, HeadroomPercent: test.headroom}}
+		p, err := NewPowerLimiter(config, layout)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("a headroom of %0.1f%% should be rejected", test.headroom)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("a headroom of %0.1f%% should be allowed: %s", test.headroom, err)
+		} else if want := 1000 * (1 - test.headroom/100); p.budgetMilliamps != want {
+			t.Errorf("a headroom of %0.1f%% gives a budget of %0.1fmA, expected %0.1fmA", test.headroom,
+				p.budgetMilliamps, want)
+		}
+	}
+}
+
+func TestPowerLimiterScalesFramesOverBudget(t *testing.T) {
+	const pixels = 100
+	layout := &Layout{Pixels: pixels}
+	config := Config{Power: PowerConfig{
+		MilliampsPerChannel:   20,
+		IdleMilliampsPerPixel: 1,
+		SupplyMilliamps:       3000,
+		HeadroomPercent:       10,
+	}}
+
+	for _, dither := range []bool{false, true} {
+		p, err := NewPowerLimiter(config, layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cc := newTestCorrection(t, pixels, 2.2, [3]float64{1, 1, 1}, dither)
+
+		// White everywhere draws 6A, a dim frame draws a fraction of the budget
+		bright := NewFrame(layout)
+		dim := NewFrame(layout)
+		for i := range bright.pixels {
+			bright.pixels[i] = colorful.Color{R: 1, G: 1, B: 1}
+			dim.pixels[i] = colorful.Color{R: 0.3, G: 0.2, B: float64(i) / pixels}
+		}
+
+		for i := 0; i < 10; i++ {
+			levels := cc.Levels(bright)
+			p.Apply(levels)
+			milliamps := p.Estimate(cc.Quantise(bright, levels).RGB())
+			if milliamps > 2700 || milliamps < 2600 {
+				t.Errorf("dither %t: a bright frame was limited to %0.0fmA, the budget is 2700mA", dither, milliamps)
+			}
+		}
+
+		levels := cc.Levels(dim)
+		unlimited := append([]float64{}, levels...)
+		p.Apply(levels)
+		for i := range levels {
+			if levels[i] != unlimited[i] {
+				t.Fatalf("dither %t: channel %d of a dim frame was changed from %0.2f to %0.2f", dither, i,
+					unlimited[i], levels[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package stream
+
+import (
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func mustHex(hex string) colorful.Color {
+	c, err := colorful.Hex(hex)
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// animationPresets are the looks that we've found work well, they can be used in playlists by name instead of
+// an animation type.
+var animationPresets = newAnimationPresets()
+
+func newAnimationPresets() map[string]AnimationPreset {
+	brightPurple := colorful.Hcl(328.0, 1.0, 0.06)
+	brightPink := colorful.Color{R: 0.45, G: -0.54, B: 0.02}
+	brightOrange := colorful.Color{R: 0.23, G: 0.04, B: -0.87}
+	brightRed := colorful.Color{R: 0.8, G: 0.0, B: 0.00}
+	brightWhite := colorful.Color{R: 0.08, G: 0.08, B: 0.08}
+	brightBlue := colorful.Hcl(280.0, 1.0, 0.06)
+	brightGold := colorful.Hcl(95.0, 1.0, 0.06)
+
+	gold := mustHex("#050401")
+	pink := mustHex("#100505")
+	purple := mustHex("#050005")
+	silver := mustHex("#030303")
+	blue := mustHex("#000005")
+	green := mustHex("#000500")
+	red := mustHex("#050000")
+	white := mustHex("#202020")
+
+	lightOrange := colorful.Hcl(88.0, 0.2, 0.04)
+	deepOrange := colorful.Hcl(89.0, 0.8, 0.04)
+	cream := colorful.Hcl(88.0, 0.04, 0.04)
+	turquoise := colorful.Hcl(185.0, 0.04, 0.02)
+	seventies := []colorful.Color{
+		lightOrange,
+		deepOrange,
+		cream,
+		turquoise,
+	}
+
+	return map[string]AnimationPreset{
+		"streak:random":  {"streak", Params{"colour": blue}},
+		"twinkle:blue":   {"twinkle", Params{"colours": []colorful.Color{blue}, "chanceRange": 40}},
+		"twinkle:pink":   {"twinkle", Params{"colours": []colorful.Color{pink}, "chanceRange": 40}},
+		"twinkle:random": {"twinkle", Params{"count": 1, "chanceRange": 50}},
+		"twinkle:silver": {"twinkle", Params{"colours": []colorful.Color{silver}, "chanceRange": 40}},
+		"twinkle:gold":   {"twinkle", Params{"colours": []colorful.Color{gold}, "chanceRange": 40}},
+		"multi:random":   {"twinkle", Params{}},
+		"multi:random2":  {"twinkle", Params{"count": 2}},
+		"multi:random3":  {"twinkle", Params{"count": 3}},
+
+		"multi:purplegoldblue": {"twinkle", Params{"colours": []colorful.Color{purple, gold, blue}, "chanceRange": 50}},
+		"multi:pinksilverblue": {"twinkle", Params{"colours": []colorful.Color{pink, silver, blue}, "chanceRange": 50}},
+		"multi:redgreengold":   {"twinkle", Params{"colours": []colorful.Color{red, green, gold}, "chanceRange": 50}},
+		"multi:redwhiteblue":   {"twinkle", Params{"colours": []colorful.Color{red, white, blue}, "chanceRange": 50}},
+		"multi:pinkblue": {"twinkle", Params{"colours": []colorful.Color{
+			{R: 0.040, G: 0.000, B: 0.011},
+			{R: 0.000, G: 0.024, B: 0.040},
+			{R: 0.005, G: 0.000, B: 0.040}}, "chanceRange": 50}},
+		"multi:monokai": {"twinkle", Params{"colours": []colorful.Color{
+			{R: 0.020, G: 0.040, B: 0.000},
+			{R: 0.012, G: 0.000, B: 0.040},
+			{R: 0.000, G: 0.040, B: 0.019},
+			{R: 0.040, G: 0.000, B: 0.005},
+			{R: 0.033, G: 0.040, B: 0.000},
+			{R: 0.040, G: 0.000, B: 0.010},
+			{R: 0.012, G: 0.040, B: 0.000}}, "chanceRange": 50}},
+
+		"rainbow:normal": {"rainbow", Params{"saturation": 1.0, "trailLength": 1200, "speed": -0.5}},
+		"rainbow:random": {"rainbow", Params{}},
+		"rainbow:fixed":  {"rainbow", Params{"saturation": 1.0, "trailLength": 0, "speed": 0.0}},
+
+		"gradient:rainbowstep": {"gradient", Params{"gradient": "rainbowstep", "trailLength": 1000}},
+		"gradient:pinkorangewhite": {"gradient", Params{
+			"colours": []colorful.Color{brightPink, brightOrange, brightWhite}, "trailLength": 310, "speed": -0.3}},
+		"gradient:purplegoldblue": {"gradient", Params{
+			"colours": []colorful.Color{brightPurple, brightGold, brightBlue}, "trailLength": 310, "speed": -0.3}},
+		"stripes:candycane": {"gradient", Params{"colours": []colorful.Color{brightRed, brightWhite}, "trailLength": 320}},
+		"stripes:random":    {"stripes", Params{}},
+
+		"istripe:random": {"istripe", Params{}},
+		"istripe:70s":    {"istripe", Params{"colours": seventies}},
+	}
+}
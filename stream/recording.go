@@ -0,0 +1,238 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Recordings start with a header of the magic bytes, a version and the start time in Unix nanoseconds. Each
+// frame is then stored as a record of the time since the start in microseconds, the length of the data and
+// the data itself, which is exactly what MarshalBinary produced for the frame that was sent.
+const (
+	recordingMagic           = "LTXR"
+	recordingVersion   uint8 = 1
+	recordingExtension       = ".ltxr"
+	recordHeaderLength       = 12
+	maxRecordLength          = 3 + (65535 * 3) // The most that MarshalBinary can produce
+)
+
+// A Recorder writes every frame that's sent to a file so that it can be replayed later.
+type Recorder struct {
+	file   *os.File
+	writer *bufio.Writer
+	start  time.Time
+	now    func() time.Time
+	lock   sync.Mutex
+}
+
+// NewRecorder creates an instance of a Recorder that writes to a new timestamped file in dir.
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	filePath := filepath.Join(dir, fmt.Sprintf("ledtx-%s%s", start.Format("20060102-150405"), recordingExtension))
+	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0664)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newRecorder(f, time.Now)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.file = f
+
+	log.Printf("Recording frames to %s", filePath)
+	return r, nil
+}
+
+// newRecorder creates a Recorder that writes to w, frames are timed with the now clock.
+func newRecorder(w io.Writer, now func() time.Time) (*Recorder, error) {
+	r := new(Recorder)
+	r.writer = bufio.NewWriter(w)
+	r.now = now
+	r.start = now()
+
+	header := make([]byte, len(recordingMagic)+1+8)
+	copy(header, recordingMagic)
+	header[len(recordingMagic)] = recordingVersion
+	binary.LittleEndian.PutUint64(header[len(recordingMagic)+1:], uint64(r.start.UnixNano()))
+	if _, err := r.writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Record appends a frame to the recording.
+func (r *Recorder) Record(f *Frame) error {
+	data, err := f.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	header := make([]byte, recordHeaderLength)
+	binary.LittleEndian.PutUint64(header, uint64(r.now().Sub(r.start).Microseconds()))
+	binary.LittleEndian.PutUint32(header[8:], uint32(len(data)))
+	if _, err := r.writer.Write(header); err != nil {
+		return err
+	}
+
+	if _, err := r.writer.Write(data); err != nil {
+		return err
+	}
+
+	// Flush every frame so that a recording is still useful if the process is killed
+	return r.writer.Flush()
+}
+
+// Close finishes the recording.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	err := r.writer.Flush()
+	if r.file != nil {
+		if closeErr := r.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// A Replayer reads a recording and sends its frames at their original timing.
+type Replayer struct {
+	reader  *bufio.Reader
+	file    *os.File
+	started time.Time
+	now     func() time.Time
+	sleep   func(time.Duration)
+}
+
+// NewReplayer creates an instance of a Replayer for a recording file.
+func NewReplayer(filePath string) (*Replayer, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newReplayer(f, filePath)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.file = f
+
+	return r, nil
+}
+
+// newReplayer creates a Replayer that reads the recording called name from rd.
+func newReplayer(rd io.Reader, name string) (*Replayer, error) {
+	r := new(Replayer)
+	r.reader = bufio.NewReader(rd)
+	r.now = time.Now
+	r.sleep = time.Sleep
+
+	header := make([]byte, len(recordingMagic)+1+8)
+	if _, err := io.ReadFull(r.reader, header); err != nil {
+		return nil, fmt.Errorf("failed to read recording header. %s", err)
+	}
+
+	if string(header[:len(recordingMagic)]) != recordingMagic {
+		return nil, fmt.Errorf("%s isn't a recording", name)
+	}
+
+	if header[len(recordingMagic)] != recordingVersion {
+		return nil, fmt.Errorf("unsupported recording version %d", header[len(recordingMagic)])
+	}
+
+	r.started = time.Unix(0, int64(binary.LittleEndian.Uint64(header[len(recordingMagic)+1:])))
+	return r, nil
+}
+
+// Started gets the time that the recording was started.
+func (r *Replayer) Started() time.Time {
+	return r.started
+}
+
+// Next reads the next frame and the time it was sent relative to the start of the recording. It returns
+// io.EOF at the end of the recording.
+func (r *Replayer) Next() (*Frame, time.Duration, error) {
+	header := make([]byte, recordHeaderLength)
+	if _, err := io.ReadFull(r.reader, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			log.Println("Recording ends with a partial frame")
+			return nil, 0, io.EOF
+		}
+		return nil, 0, err
+	}
+
+	offset := time.Duration(binary.LittleEndian.Uint64(header)) * time.Microsecond
+	length := binary.LittleEndian.Uint32(header[8:])
+	if length > maxRecordLength {
+		return nil, 0, fmt.Errorf("recording has a %d byte frame, it's probably corrupt", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r.reader, data); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			log.Println("Recording ends with a partial frame")
+			return nil, 0, io.EOF
+		}
+		return nil, 0, err
+	}
+
+	f := new(Frame)
+	if err := f.UnmarshalBinary(data); err != nil {
+		return nil, 0, err
+	}
+
+	return f, offset, nil
+}
+
+// Run sends every frame in the recording to a Transport, waiting so that each frame goes out at the same
+// offset from the start as it was recorded.
+func (r *Replayer) Run(transport Transport) error {
+	if r.file != nil {
+		defer r.file.Close()
+	}
+
+	log.Printf("Replaying a recording started at %s", r.started.Format(time.RFC3339))
+	start := r.now()
+	count := 0
+	for {
+		f, offset, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if wait := offset - r.now().Sub(start); wait > 0 {
+			r.sleep(wait)
+		}
+
+		if err := transport.Send(f); err != nil {
+			log.Printf("Failed to send frame. %s", err)
+		}
+		count++
+	}
+
+	log.Printf("Replayed %d frames in %s", count, r.now().Sub(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,129 @@
+package stream
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// memoryTransport keeps the frames that are sent and when they were sent.
+type memoryTransport struct {
+	now    func() time.Time
+	frames [][]byte
+	times  []time.Time
+}
+
+func (m *memoryTransport) Send(f *Frame) error {
+	data, err := f.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	m.frames = append(m.frames, data)
+	m.times = append(m.times, m.now())
+	return nil
+}
+
+func (m *memoryTransport) SetAckHandler(handler AckHandler) {}
+
+func TestRecordingReplaysTheSameFrames(t *testing.T) {
+	now := at(16, 12, 0)
+	clock := func() time.Time { return now }
+
+	var buf bytes.Buffer
+	recorder, err := newRecorder(&buf, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, f := testFrame(t, 50)
+	offsets := []time.Duration{0, 21 * time.Millisecond, 42 * time.Millisecond, 100 * time.Millisecond}
+	var sent [][]byte
+	for i, offset := range offsets {
+		now = at(16, 12, 0).Add(offset)
+		f.ackID = uint8(i)
+		f.pixels[0].B = float64(i) / 10.0
+		data, _ := f.MarshalBinary()
+		sent = append(sent, data)
+		if err := recorder.Record(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayer, err := newReplayer(bytes.NewReader(buf.Bytes()), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !replayer.Started().Equal(at(16, 12, 0)) {
+		t.Errorf("the recording started at %s, expected %s", replayer.Started(), at(16, 12, 0))
+	}
+
+	// Replay on a clock that only moves when the replayer sleeps
+	replayNow := at(17, 9, 0)
+	replayer.now = func() time.Time { return replayNow }
+	replayer.sleep = func(d time.Duration) { replayNow = replayNow.Add(d) }
+	transport := &memoryTransport{now: replayer.now}
+	if err := replayer.Run(transport); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(transport.frames) != len(sent) {
+		t.Fatalf("replayed %d frames, expected %d", len(transport.frames), len(sent))
+	}
+	for i := range sent {
+		if !bytes.Equal(transport.frames[i], sent[i]) {
+			t.Errorf("frame %d was replayed as %v, expected %v", i, transport.frames[i][:6], sent[i][:6])
+		}
+		if got := transport.times[i].Sub(at(17, 9, 0)); got != offsets[i] {
+			t.Errorf("frame %d was replayed at %s, expected %s", i, got, offsets[i])
+		}
+	}
+}
+
+func TestReplayerRejectsBadRecordings(t *testing.T) {
+	var buf bytes.Buffer
+	recorder, err := newRecorder(&buf, time.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, f := testFrame(t, 10)
+	recorder.Record(f)
+	recorder.Close()
+	recording := buf.Bytes()
+
+	if _, err := newReplayer(bytes.NewReader(recording[:5]), "truncated"); err == nil {
+		t.Error("a recording with a truncated header should be rejected")
+	}
+
+	badMagic := append([]byte("NOPE"), recording[4:]...)
+	if _, err := newReplayer(bytes.NewReader(badMagic), "bad magic"); err == nil {
+		t.Error("a recording with the wrong magic should be rejected")
+	}
+
+	// A frame that was cut off when the recorder was killed ends the replay
+	replayer, err := newReplayer(bytes.NewReader(recording[:len(recording)-4]), "partial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &memoryTransport{now: time.Now}
+	if err := replayer.Run(transport); err != nil {
+		t.Errorf("a partial frame at the end should end the replay: %s", err)
+	}
+	if len(transport.frames) != 0 {
+		t.Errorf("replayed %d frames, the only one was partial", len(transport.frames))
+	}
+
+	// A corrupt length is an error
+	corrupt := append([]byte{}, recording...)
+	copy(corrupt[len(recordingMagic)+1+8+8:], []byte{0xff, 0xff, 0xff, 0xff})
+	replayer, err = newReplayer(bytes.NewReader(corrupt), "corrupt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := replayer.Run(transport); err == nil {
+		t.Error("a frame with a corrupt length should be rejected")
+	}
+}
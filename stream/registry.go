@@ -0,0 +1,292 @@
+package stream
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// ParamType is the type of value that a parameter holds.
+type ParamType string
+
+// The types of parameter that animations can take.
+const (
+	ParamInt     ParamType = "int"
+	ParamFloat   ParamType = "float"
+	ParamString  ParamType = "string"
+	ParamColour  ParamType = "colour"
+	ParamColours ParamType = "colours"
+)
+
+// A ParamSpec describes a parameter that an animation accepts. Min and Max limit the value of numbers and the
+// length of colour lists.
+type ParamSpec struct {
+	Name        string      `json:"name"`
+	Type        ParamType   `json:"type"`
+	Description string      `json:"description"`
+	Min         float64     `json:"min,omitempty"`
+	Max         float64     `json:"max,omitempty"`
+	Options     []string    `json:"options,omitempty"`
+	Default     interface{} `json:"default"` // A nil default means that a value is chosen at random
+}
+
+// AnimationContext holds what an animation needs to know about the Controller that's creating it.
+type AnimationContext struct {
+	Layout        *Layout
+	RuntimeMs     int64
+	SaturationMin float64
+	SaturationMax float64
+	Rand          *rand.Rand // Every random choice comes from here so that animations can be reproduced
+	PixelMap      *PixelMap  // Where each pixel is on the tree, nil until the lights have been calibrated
+}
+
+// An AnimationConstructor creates an Animation from parameters that have already been validated.
+type AnimationConstructor func(ctx *AnimationContext, params Params) (Animation, error)
+
+// An AnimationType is an animation that can be created by name.
+type AnimationType struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Params      []ParamSpec          `json:"params"`
+	New         AnimationConstructor `json:"-"`
+}
+
+// An AnimationPreset is a named set of parameters for an animation type.
+type AnimationPreset struct {
+	Type   string `json:"type"`
+	Params Params `json:"params"`
+}
+
+var animationTypes = make(map[string]*AnimationType)
+
+// RegisterAnimation makes an animation type available by name. It's intended to be called from init functions
+// so it panics if the type is invalid.
+func RegisterAnimation(t AnimationType) {
+	if _, ok := animationTypes[t.Name]; ok {
+		panic(fmt.Sprintf("animation type %s is already registered", t.Name))
+	}
+
+	for _, spec := range t.Params {
+		if spec.Default != nil {
+			if _, err := spec.validate(spec.Default); err != nil {
+				panic(fmt.Sprintf("animation type %s has an invalid default. %s", t.Name, err))
+			}
+		}
+	}
+
+	animationTypes[t.Name] = &t
+}
+
+// AnimationTypes gets every registered animation type sorted by name.
+func AnimationTypes() []AnimationType {
+	types := make([]AnimationType, 0, len(animationTypes))
+	for _, t := range animationTypes {
+		types = append(types, *t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+
+	return types
+}
+
+// AnimationPresets gets the names of every preset sorted by name.
+func AnimationPresets() []string {
+	names := make([]string, 0, len(animationPresets))
+	for name := range animationPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// resolveAnimation finds the type for an animation name, which is either a type or a preset. The params are
+// validated and merged over those of the preset.
+func resolveAnimation(name string, params Params) (*AnimationType, Params, error) {
+	t, ok := animationTypes[name]
+	merged := make(Params)
+	if !ok {
+		preset, found := animationPresets[name]
+		if !found {
+			return nil, nil, fmt.Errorf("unknown animation %q", name)
+		}
+
+		t, ok = animationTypes[preset.Type]
+		if !ok {
+			return nil, nil, fmt.Errorf("preset %q has an unknown animation type %q", name, preset.Type)
+		}
+
+		for k, v := range preset.Params {
+			merged[k] = v
+		}
+	}
+
+	for k, v := range params {
+		merged[k] = v
+	}
+
+	resolved, err := t.validate(merged)
+	if err != nil {
+		return nil, nil, fmt.Errorf("animation %q: %s", name, err)
+	}
+
+	return t, resolved, nil
+}
+
+// ValidateAnimation checks that an animation can be created without creating it.
+func ValidateAnimation(name string, params Params) error {
+	_, _, err := resolveAnimation(name, params)
+	return err
+}
+
+// NewAnimation creates an animation from a type or preset name and its parameters. The params that are returned
+// have every value that was used, including those that were chosen at random.
+func NewAnimation(name string, params Params, ctx *AnimationContext) (Animation, Params, error) {
+	t, resolved, err := resolveAnimation(name, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	animation, err := t.New(ctx, resolved)
+	if err != nil {
+		return nil, nil, fmt.Errorf("animation %q: %s", name, err)
+	}
+
+	return animation, resolved, nil
+}
+
+func (t *AnimationType) validate(params Params) (Params, error) {
+	resolved := make(Params, len(t.Params))
+	for name := range params {
+		if t.spec(name) == nil {
+			return nil, fmt.Errorf("unknown parameter %s", name)
+		}
+	}
+
+	for _, spec := range t.Params {
+		value, ok := params[spec.Name]
+		if !ok {
+			value = spec.Default
+		}
+
+		if value == nil {
+			continue
+		}
+
+		v, err := spec.validate(value)
+		if err != nil {
+			return nil, err
+		}
+		resolved[spec.Name] = v
+	}
+
+	return resolved, nil
+}
+
+func (t *AnimationType) spec(name string) *ParamSpec {
+	for i := range t.Params {
+		if t.Params[i].Name == name {
+			return &t.Params[i]
+		}
+	}
+
+	return nil
+}
+
+func (spec *ParamSpec) checkRange(value float64) error {
+	if (spec.Min != 0 || spec.Max != 0) && (value < spec.Min || value > spec.Max) {
+		return fmt.Errorf("parameter %s must be between %v and %v", spec.Name, spec.Min, spec.Max)
+	}
+
+	return nil
+}
+
+// validate checks a value against the spec and converts it to the type that the constructors expect.
+func (spec *ParamSpec) validate(value interface{}) (interface{}, error) {
+	switch spec.Type {
+	case ParamInt:
+		var i int
+		switch v := value.(type) {
+		case int:
+			i = v
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("parameter %s must be a whole number", spec.Name)
+			}
+			i = int(v)
+		default:
+			return nil, fmt.Errorf("parameter %s must be a number", spec.Name)
+		}
+		return i, spec.checkRange(float64(i))
+
+	case ParamFloat:
+		var f float64
+		switch v := value.(type) {
+		case int:
+			f = float64(v)
+		case float64:
+			f = v
+		default:
+			return nil, fmt.Errorf("parameter %s must be a number", spec.Name)
+		}
+		return f, spec.checkRange(f)
+
+	case ParamString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("parameter %s must be a string", spec.Name)
+		}
+
+		if len(spec.Options) > 0 {
+			for _, option := range spec.Options {
+				if s == option {
+					return s, nil
+				}
+			}
+			return nil, fmt.Errorf("parameter %s must be one of %v", spec.Name, spec.Options)
+		}
+		return s, nil
+
+	case ParamColour:
+		colour, err := parseColour(value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %s", spec.Name, err)
+		}
+		return colour, nil
+
+	case ParamColours:
+		colours, err := parseColours(value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %s", spec.Name, err)
+		}
+
+		if err := spec.checkRange(float64(len(colours))); err != nil {
+			return nil, fmt.Errorf("parameter %s must have between %v and %v colours", spec.Name, spec.Min, spec.Max)
+		}
+		return colours, nil
+	}
+
+	return nil, fmt.Errorf("parameter %s has an unknown type %s", spec.Name, spec.Type)
+}
+
+// parseColours reads a list of colours in any of the forms accepted by parseColour.
+func parseColours(value interface{}) ([]colorful.Color, error) {
+	switch v := value.(type) {
+	case []colorful.Color:
+		return v, nil
+	case []interface{}:
+		colours := make([]colorful.Color, len(v))
+		for i, c := range v {
+			colour, err := parseColour(c)
+			if err != nil {
+				return nil, err
+			}
+			colours[i] = colour
+		}
+		return colours, nil
+	}
+
+	return nil, fmt.Errorf("%v should be a list of colours", value)
+}
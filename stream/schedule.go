@@ -0,0 +1,311 @@
+package stream
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const defaultScheduleInterval = 15 * time.Second
+
+// A Clock tells the Scheduler what time it is, it can be replaced to run the schedule at other times.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ScheduleWindow is a time of day when the lights are on. A window that ends before it starts runs past
+// midnight, and it belongs to the day that it starts on. Times are HH:MM, or sunrise or sunset with an
+// optional offset like sunset-30m.
+type ScheduleWindow struct {
+	Name       string   `yaml:"name"`
+	Days       []string `yaml:"days"` // mon, tue, etc. or empty for every day
+	Start      string   `yaml:"start"`
+	End        string   `yaml:"end"`
+	Playlist   string   `yaml:"playlist"`   // Empty to keep the current playlist
+	Brightness float64  `yaml:"brightness"` // Caps the brightness, zero means no cap
+}
+
+// ScheduleConfig describes when the lights are on. Without any windows they're always on. The latitude and
+// longitude are needed for times that are relative to sunrise and sunset.
+type ScheduleConfig struct {
+	Windows   []ScheduleWindow `yaml:"windows"`
+	Outside   string           `yaml:"outside"`  // black (the default) to send black frames or off to stop sending
+	Interval  time.Duration    `yaml:"interval"` // How often the clock is checked
+	Latitude  *float64         `yaml:"latitude"`
+	Longitude *float64         `yaml:"longitude"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// scheduleTime is a time of day that's either fixed or relative to sunrise or sunset.
+type scheduleTime struct {
+	event     string        // Empty for a fixed time, otherwise sunrise or sunset
+	offset    time.Duration // The time since midnight, or the offset from the event
+	latitude  float64
+	longitude float64
+}
+
+// parseScheduleTime reads an HH:MM time, or sunrise or sunset with an optional offset like sunset-30m.
+func parseScheduleTime(value string, config ScheduleConfig) (scheduleTime, error) {
+	for _, event := range []string{"sunrise", "sunset"} {
+		if !strings.HasPrefix(value, event) {
+			continue
+		}
+
+		if config.Latitude == nil || config.Longitude == nil {
+			return scheduleTime{}, fmt.Errorf("time %q needs the schedule's latitude and longitude", value)
+		}
+
+		st := scheduleTime{event: event, latitude: *config.Latitude, longitude: *config.Longitude}
+		if offset := strings.TrimPrefix(value, event); offset != "" {
+			if offset[0] != '+' && offset[0] != '-' {
+				return scheduleTime{}, fmt.Errorf("time %q should have an offset like %s-30m", value, event)
+			}
+
+			var err error
+			if st.offset, err = time.ParseDuration(offset); err != nil {
+				return scheduleTime{}, fmt.Errorf("time %q has an invalid offset. %s", value, err)
+			}
+		}
+
+		return st, nil
+	}
+
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return scheduleTime{}, fmt.Errorf("time %q should be HH:MM, sunrise or sunset", value)
+	}
+
+	return scheduleTime{offset: time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute}, nil
+}
+
+// on gets the time on the date of day, ok is false if the sun doesn't rise or set that day.
+func (st scheduleTime) on(day time.Time) (time.Time, bool) {
+	if st.event == "" {
+		hours := int(st.offset / time.Hour)
+		minutes := int((st.offset % time.Hour) / time.Minute)
+		return time.Date(day.Year(), day.Month(), day.Day(), hours, minutes, 0, 0, day.Location()), true
+	}
+
+	sunrise, sunset, ok := sunTimes(day, st.latitude, st.longitude)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	if st.event == "sunrise" {
+		return sunrise.Add(st.offset), true
+	}
+	return sunset.Add(st.offset), true
+}
+
+// scheduleWindow is a ScheduleWindow that has been parsed.
+type scheduleWindow struct {
+	ScheduleWindow
+	days  [7]bool
+	start scheduleTime
+	end   scheduleTime
+}
+
+func newScheduleWindow(config ScheduleWindow, schedule ScheduleConfig, playlists []string) (*scheduleWindow, error) {
+	w := new(scheduleWindow)
+	w.ScheduleWindow = config
+	if w.Name == "" {
+		w.Name = fmt.Sprintf("%s-%s", config.Start, config.End)
+	}
+
+	if len(config.Days) == 0 {
+		for i := range w.days {
+			w.days[i] = true
+		}
+	}
+
+	for _, day := range config.Days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return nil, fmt.Errorf("window %s: unknown day %q", w.Name, day)
+		}
+		w.days[weekday] = true
+	}
+
+	var err error
+	if w.start, err = parseScheduleTime(config.Start, schedule); err != nil {
+		return nil, fmt.Errorf("window %s: %s", w.Name, err)
+	}
+	if w.end, err = parseScheduleTime(config.End, schedule); err != nil {
+		return nil, fmt.Errorf("window %s: %s", w.Name, err)
+	}
+
+	if w.Brightness < 0.0 || w.Brightness > 1.0 {
+		return nil, fmt.Errorf("window %s: brightness %0.2f should be between 0 and 1", w.Name, w.Brightness)
+	}
+
+	if w.Playlist != "" {
+		found := false
+		for _, name := range playlists {
+			found = found || name == w.Playlist
+		}
+		if !found {
+			return nil, fmt.Errorf("window %s: unknown playlist %q", w.Name, w.Playlist)
+		}
+	}
+
+	return w, nil
+}
+
+// contains checks whether the window is open at a point in time.
+func (w *scheduleWindow) contains(t time.Time) bool {
+	// A window that runs past midnight could have opened yesterday
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for _, day := range []time.Time{today, today.AddDate(0, 0, -1)} {
+		if !w.days[day.Weekday()] {
+			continue
+		}
+
+		opens, ok := w.start.on(day)
+		if !ok {
+			continue
+		}
+
+		closes, ok := w.end.on(day)
+		if ok && !closes.After(opens) {
+			closes, ok = w.end.on(day.AddDate(0, 0, 1))
+		}
+		if !ok {
+			continue
+		}
+
+		if !t.Before(opens) && t.Before(closes) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// brightnessCap gets the maximum brightness while the window is open.
+func (w *scheduleWindow) brightnessCap() float64 {
+	if w.Brightness == 0.0 {
+		return 1.0
+	}
+
+	return w.Brightness
+}
+
+// Scheduler turns the lights on and off at times of day, choosing playlists and brightness for each window.
+type Scheduler struct {
+	controller *Controller
+	clock      Clock
+	windows    []*scheduleWindow
+	silent     bool
+	interval   time.Duration
+	active     *scheduleWindow
+	started    bool
+}
+
+// NewScheduler creates an instance of a Scheduler, a nil clock uses the system clock.
+func NewScheduler(config ScheduleConfig, controller *Controller, clock Clock) (*Scheduler, error) {
+	s := new(Scheduler)
+	s.controller = controller
+	s.clock = clock
+	if s.clock == nil {
+		s.clock = systemClock{}
+	}
+
+	switch config.Outside {
+	case "", "black":
+		s.silent = false
+	case "off":
+		s.silent = true
+	default:
+		return nil, fmt.Errorf("schedule outside should be black or off, not %q", config.Outside)
+	}
+
+	s.interval = config.Interval
+	if s.interval <= 0 {
+		s.interval = defaultScheduleInterval
+	}
+
+	playlists := controller.Status().Playlists
+	s.windows = make([]*scheduleWindow, 0, len(config.Windows))
+	for _, windowConfig := range config.Windows {
+		w, err := newScheduleWindow(windowConfig, config, playlists)
+		if err != nil {
+			return nil, err
+		}
+		s.windows = append(s.windows, w)
+	}
+
+	return s, nil
+}
+
+// Enabled reports whether there are any windows, without them the lights are always on.
+func (s *Scheduler) Enabled() bool {
+	return len(s.windows) > 0
+}
+
+// activeWindow finds the first window that's open at a point in time, nil if they're all closed.
+func (s *Scheduler) activeWindow(t time.Time) *scheduleWindow {
+	for _, w := range s.windows {
+		if w.contains(t) {
+			return w
+		}
+	}
+
+	return nil
+}
+
+// Update checks the clock and tells the Controller when a window opens or closes.
+func (s *Scheduler) Update() {
+	if !s.Enabled() {
+		return
+	}
+
+	active := s.activeWindow(s.clock.Now())
+	if s.started && active == s.active {
+		return
+	}
+	s.started = true
+	s.active = active
+
+	if active == nil {
+		log.Println("Schedule: outside of every window, turning the lights off")
+		s.controller.SetSchedule(ScheduleState{On: false, Silent: s.silent, BrightnessCap: 1.0})
+		return
+	}
+
+	log.Printf("Schedule: window %s is open", active.Name)
+	if active.Playlist != "" && active.Playlist != s.controller.Status().Playlist {
+		if err := s.controller.SetPlaylist(active.Playlist); err != nil {
+			log.Printf("Schedule: failed to switch to playlist %s. %s", active.Playlist, err)
+		}
+	}
+	s.controller.SetSchedule(ScheduleState{Window: active.Name, On: true, BrightnessCap: active.brightnessCap()})
+}
+
+// Run checks the schedule periodically.
+func (s *Scheduler) Run() {
+	if !s.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.Update()
+	}
+}
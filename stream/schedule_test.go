@@ -0,0 +1,234 @@
+package stream
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// at gets a time in October 2026, the 16th is a Friday.
+func at(day int, hour int, minute int) time.Time {
+	return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+}
+
+func newTestScheduler(t *testing.T, config ScheduleConfig, clock Clock) (*Scheduler, *Controller) {
+	playlists := map[string]Playlist{
+		"party": {{Animation: "solid"}},
+		"calm":  {{Animation: "solid"}},
+	}
+	controller := newTestController(t, 10, 1, playlists, "party")
+
+	s, err := NewScheduler(config, controller, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, controller
+}
+
+func TestScheduleWindowAcrossMidnight(t *testing.T) {
+	config := ScheduleConfig{Windows: []ScheduleWindow{
+		{Name: "late", Days: []string{"fri"}, Start: "19:00", End: "01:00"},
+	}}
+	s, _ := newTestScheduler(t, config, nil)
+
+	for _, tc := range []struct {
+		when time.Time
+		open bool
+	}{
+		{at(16, 18, 59), false},
+		{at(16, 19, 0), true},
+		{at(16, 23, 59), true},
+		{at(17, 0, 30), true}, // Saturday morning, but the window opened on Friday
+		{at(17, 1, 0), false},
+		{at(17, 19, 30), false},
+		{at(15, 23, 0), false},
+	} {
+		if open := s.activeWindow(tc.when) != nil; open != tc.open {
+			t.Errorf("at %s the window should be open %t", tc.when.Format("Mon 15:04"), tc.open)
+		}
+	}
+}
+
+func TestScheduleWindowDays(t *testing.T) {
+	config := ScheduleConfig{Windows: []ScheduleWindow{
+		{Name: "weekend", Days: []string{"sat", "Sun"}, Start: "10:00", End: "12:00"},
+		{Name: "daily", Start: "20:00", End: "21:00"},
+	}}
+	s, _ := newTestScheduler(t, config, nil)
+
+	for _, tc := range []struct {
+		when   time.Time
+		window string
+	}{
+		{at(17, 11, 0), "weekend"},
+		{at(18, 11, 0), "weekend"},
+		{at(19, 11, 0), ""},
+		{at(16, 11, 0), ""},
+		{at(19, 20, 30), "daily"},
+		{at(17, 20, 30), "daily"},
+	} {
+		name := ""
+		if w := s.activeWindow(tc.when); w != nil {
+			name = w.Name
+		}
+		if name != tc.window {
+			t.Errorf("at %s the open window is %q, expected %q", tc.when.Format("Mon 15:04"), name, tc.window)
+		}
+	}
+}
+
+func TestScheduleRejectsUnknownDaysAndPlaylists(t *testing.T) {
+	controller := newTestController(t, 10, 1, nil, defaultPlaylistName)
+	for _, w := range []ScheduleWindow{
+		{Days: []string{"someday"}, Start: "10:00", End: "11:00"},
+		{Start: "10:00", End: "11:00", Playlist: "missing"},
+		{Start: "10:00", End: "11:00", Brightness: 2},
+	} {
+		if _, err := NewScheduler(ScheduleConfig{Windows: []ScheduleWindow{w}}, controller, nil); err == nil {
+			t.Errorf("window %+v should be rejected", w)
+		}
+	}
+}
+
+func TestScheduleBrightnessCap(t *testing.T) {
+	clock := &fakeClock{now: at(16, 19, 30)}
+	config := ScheduleConfig{Windows: []ScheduleWindow{
+		{Name: "dim", Start: "19:00", End: "20:00", Brightness: 0.4},
+		{Name: "bright", Start: "20:00", End: "21:00"},
+	}}
+	s, controller := newTestScheduler(t, config, clock)
+
+	s.Update()
+	state := controller.Status().Schedule
+	if !state.On || state.Window != "dim" || state.BrightnessCap != 0.4 {
+		t.Fatalf("the dim window should be on with a cap of 0.4, got %+v", state)
+	}
+
+	// The solid animation is white, so every channel shows the cap
+	f := controller.CalculateFrame(0)
+	if r := f.pixels[0].R; r < 0.4-1e-9 || r > 0.4+1e-9 {
+		t.Errorf("the frame should be capped at 0.4, got %0.2f", r)
+	}
+
+	// A higher brightness doesn't lift the cap
+	controller.SetBrightness(1.0)
+	if r := controller.CalculateFrame(10).pixels[0].R; r < 0.4-1e-9 || r > 0.4+1e-9 {
+		t.Errorf("the frame should still be capped at 0.4, got %0.2f", r)
+	}
+
+	clock.now = at(16, 20, 30)
+	s.Update()
+	if state := controller.Status().Schedule; state.Window != "bright" || state.BrightnessCap != 1.0 {
+		t.Fatalf("the bright window shouldn't have a cap, got %+v", state)
+	}
+}
+
+func TestSchedulePlaylistsAndOutside(t *testing.T) {
+	clock := &fakeClock{now: at(16, 17, 0)}
+	config := ScheduleConfig{
+		Outside: "off",
+		Windows: []ScheduleWindow{
+			{Name: "evening", Start: "18:00", End: "22:00", Playlist: "calm"},
+			{Name: "party", Start: "22:00", End: "02:00", Playlist: "party"},
+		},
+	}
+	s, controller := newTestScheduler(t, config, clock)
+
+	s.Update()
+	if state := controller.Status().Schedule; state.On || !state.Silent {
+		t.Fatalf("outside of the windows the lights should be silent, got %+v", state)
+	}
+	if f := controller.CalculateFrame(0); f != nil {
+		t.Error("no frames should be sent outside of the windows")
+	}
+
+	for _, tc := range []struct {
+		when     time.Time
+		playlist string
+	}{
+		{at(16, 18, 0), "calm"},
+		{at(16, 23, 0), "party"},
+		{at(17, 1, 0), "party"},
+		{at(17, 18, 30), "calm"},
+	} {
+		clock.now = tc.when
+		s.Update()
+		status := controller.Status()
+		if !status.Schedule.On || status.Playlist != tc.playlist {
+			t.Errorf("at %s the playlist should be %s, got %s (on %t)", tc.when.Format("Mon 15:04"),
+				tc.playlist, status.Playlist, status.Schedule.On)
+		}
+	}
+}
+
+// windowAt moves the clock, updates the scheduler and gets the name of the open window.
+func windowAt(s *Scheduler, controller *Controller, clock *fakeClock, when time.Time) string {
+	clock.now = when
+	s.Update()
+	return controller.Status().Schedule.Window
+}
+
+func TestScheduleWindowRelativeToSunset(t *testing.T) {
+	latitude, longitude := 51.5, 0.0
+	clock := &fakeClock{now: at(16, 12, 0)}
+	config := ScheduleConfig{
+		Latitude:  &latitude,
+		Longitude: &longitude,
+		Windows:   []ScheduleWindow{{Name: "evening", Start: "sunset-30m", End: "23:00"}},
+	}
+	s, controller := newTestScheduler(t, config, clock)
+
+	_, sunset, ok := sunTimes(at(16, 0, 0), latitude, longitude)
+	if !ok {
+		t.Fatal("the sun should set in October")
+	}
+	opens := sunset.Add(-30 * time.Minute)
+
+	for _, tc := range []struct {
+		when   time.Time
+		window string
+	}{
+		{opens.Add(-time.Minute), ""},
+		{opens.Add(time.Minute), "evening"},
+		{at(16, 22, 59), "evening"},
+		{at(16, 23, 0), ""},
+		{at(17, 0, 30), ""},
+	} {
+		if window := windowAt(s, controller, clock, tc.when); window != tc.window {
+			t.Errorf("at %s the open window is %q, expected %q", tc.when.Format("Mon 15:04"), window, tc.window)
+		}
+	}
+}
+
+func TestScheduleWindowAcrossMidnightWithTheClock(t *testing.T) {
+	clock := &fakeClock{now: at(16, 12, 0)}
+	config := ScheduleConfig{Windows: []ScheduleWindow{
+		{Name: "late", Days: []string{"fri"}, Start: "22:00", End: "02:00"},
+	}}
+	s, controller := newTestScheduler(t, config, clock)
+
+	for _, tc := range []struct {
+		when   time.Time
+		window string
+	}{
+		{at(16, 21, 59), ""},
+		{at(16, 22, 0), "late"},
+		{at(16, 23, 59), "late"},
+		{at(17, 0, 0), "late"}, // Saturday, but the window opened on Friday
+		{at(17, 1, 59), "late"},
+		{at(17, 2, 0), ""},
+		{at(17, 23, 0), ""}, // It doesn't open again on Saturday night
+	} {
+		if window := windowAt(s, controller, clock, tc.when); window != tc.window {
+			t.Errorf("at %s the open window is %q, expected %q", tc.when.Format("Mon 15:04"), window, tc.window)
+		}
+	}
+}
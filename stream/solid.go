@@ -0,0 +1,44 @@
+package stream
+
+import (
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func init() {
+	RegisterAnimation(AnimationType{
+		Name:        "solid",
+		Description: "Every pixel the same colour",
+		Params: []ParamSpec{
+			{Name: "colour", Type: ParamColour, Default: "#ffffff", Description: "Colour of the pixels"},
+		},
+		New: newSolidFromParams,
+	})
+}
+
+func newSolidFromParams(ctx *AnimationContext, params Params) (Animation, error) {
+	return NewSolid(ctx.Layout, params.Colour("colour", colorful.Color{R: 1, G: 1, B: 1})), nil
+}
+
+// A Solid is an Animation that fills every pixel with a single colour.
+type Solid struct {
+	layout *Layout
+	colour colorful.Color
+}
+
+// NewSolid creates an instance of a Solid.
+func NewSolid(layout *Layout, colour colorful.Color) *Solid {
+	s := new(Solid)
+	s.layout = layout
+	s.colour = colour
+	return s
+}
+
+// CalculateFrame creates a new Frame instance.
+func (s *Solid) CalculateFrame(runtimeMs int64) *Frame {
+	f := NewFrame(s.layout)
+	for i := range f.pixels {
+		f.pixels[i] = s.colour
+	}
+
+	return f
+}
@@ -9,6 +9,23 @@ import (
 	"github.com/lucasb-eyer/go-colorful"
 )
 
+func init() {
+	RegisterAnimation(AnimationType{
+		Name:        "streak",
+		Description: "Streaks that fade in and out as they move across the tree",
+		Params: []ParamSpec{
+			{Name: "colour", Type: ParamColour, Default: "#000005", Description: "Background colour"},
+			{Name: "chance", Type: ParamInt, Min: 1, Max: 10000, Default: 100,
+				Description: "There's a 1 in chance of a new streak on every frame"},
+		},
+		New: newStreakFromParams,
+	})
+}
+
+func newStreakFromParams(ctx *AnimationContext, params Params) (Animation, error) {
+	return NewStreak(ctx.Layout, ctx.Rand, ctx.RuntimeMs, int32(params.Int("chance", 100)), params.Colour("colour", colorful.Color{})), nil
+}
+
 type streakParticle struct {
 	colour    colorful.Color
 	start     float64
@@ -68,6 +85,10 @@ func (p *streakParticle) addStreak(frame *Frame) bool {
 		start := int(math.Ceil(p.current))
 		end := int(math.Floor(p.current + p.length))
 		for i := start; i <= end; i++ {
+			// The streak can run off either end of the frame
+			if i < 0 || i >= frame.Len() {
+				continue
+			}
 			frame.pixels[i].BlendHcl(p.colour, bias)
 		}
 	}
@@ -77,15 +98,19 @@ func (p *streakParticle) addStreak(frame *Frame) bool {
 
 // A Streak is an Animation that creates streaks across the tree that fade in then out.
 type Streak struct {
+	layout       *Layout
 	backColour   colorful.Color
 	runtimeMs    int64
 	streakChance int32
 	particles    *list.List
+	rand         *rand.Rand
 }
 
 // NewStreak creates an instance of a Streak object.
-func NewStreak(runtimeMs int64, streakChance int32, backColour colorful.Color) *Streak {
+func NewStreak(layout *Layout, r *rand.Rand, runtimeMs int64, streakChance int32, backColour colorful.Color) *Streak {
 	t := new(Streak)
+	t.layout = layout
+	t.rand = r
 	t.streakChance = streakChance
 	t.backColour = backColour
 	t.runtimeMs = runtimeMs
@@ -98,8 +123,8 @@ func NewStreak(runtimeMs int64, streakChance int32, backColour colorful.Color) *
 func (s *Streak) CalculateFrame(runtimeMs int64) *Frame {
 	s.runtimeMs = runtimeMs
 
-	f := NewFrame()
-	numPixels := len(f.pixels)
+	f := NewFrame(s.layout)
+	numPixels := f.Len()
 	for i := 0; i < numPixels; i++ {
 		f.pixels[i] = s.backColour
 	}
@@ -117,7 +142,7 @@ func (s *Streak) CalculateFrame(runtimeMs int64) *Frame {
 		}
 	}
 
-	if rand.Int31n(s.streakChance) == 0 {
+	if s.rand.Int31n(s.streakChance) == 0 {
 		// Create a randomised new particle
 		p := newStreakParticle()
 		s.particles.PushBack(p)
@@ -2,63 +2,220 @@ package stream
 
 import (
 	"log"
+	"sync"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
+const statsReportInterval = 60 * time.Second
+
+// A FrameListener is called with every frame the animations render, before colour correction. It's called from
+// the streaming loop so it mustn't block or modify the frame.
+type FrameListener func(f *Frame)
+
 // Streamer that streams RGB data frames to an ledrx device.
 type Streamer struct {
-	config      Config
-	client      mqtt.Client
-	calibrate   *Calibrate
-	animation   Animation
-	frameTimeMs int64
-	runtimeMs   int64
+	config     Config
+	client     mqtt.Client
+	layout     *Layout
+	transport  Transport
+	correction *ColourCorrection
+	limiter    *PowerLimiter
+	recorder   *Recorder
+	scheduler  *FrameScheduler
+	calibrate  *Calibrate
+	controller *Controller
+	commander  *Commander
+	favourites *Favourites
+	schedule   *Scheduler
+	animation  Animation
+	listeners  []FrameListener
+
+	listenerLock sync.Mutex
 }
 
 // NewStreamer creates an instance of a Streamer.
-func NewStreamer(config Config, client mqtt.Client) *Streamer {
+func NewStreamer(config Config, client mqtt.Client) (*Streamer, error) {
+	layout, err := NewLayout(config.Layout)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Layout: %d pixels in %d segment(s)", layout.Pixels, len(layout.Segments))
+
+	transport, err := NewTransport(config, client, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	correction, err := NewColourCorrection(config, layout)
+	if err != nil {
+		return nil, err
+	}
+
 	s := new(Streamer)
 	s.config = config
 	s.client = client
-	s.frameTimeMs = 21
-	s.runtimeMs = 0
+	s.layout = layout
+	s.transport = transport
+	s.correction = correction
+	s.limiter, err = NewPowerLimiter(config, layout)
+	if err != nil {
+		return nil, err
+	}
+	s.scheduler = NewFrameScheduler(config.FrameRate)
 
 	// Use a controller as the animation, internally it will control multiple animations
-	s.calibrate = NewCalibrate(s.config, s.client)
-	frameRate := 1000.0 / float64(s.frameTimeMs)
-	log.Printf("Frame rate: %0.1f fps", frameRate)
-	c := NewController(s.runtimeMs, frameRate, 30*time.Second, s.calibrate)
+	s.calibrate, err = NewCalibrate(s.config, s.client, s.layout)
+	if err != nil {
+		return nil, err
+	}
+	s.transport.SetAckHandler(s.calibrate.HandleAck)
+	log.Printf("Frame rate: %0.1f fps", s.scheduler.FrameRate())
+	s.favourites, err = NewFavourites(config.Favourites)
+	if err != nil {
+		return nil, err
+	}
+
+	playlists, err := NewPlaylists(config, s.favourites)
+	if err != nil {
+		return nil, err
+	}
+
+	playlistName := config.Playlist
+	if playlistName == "" {
+		playlistName = defaultPlaylistName
+	}
+
+	seed := time.Now().UnixNano()
+	if config.Seed != nil {
+		seed = *config.Seed
+	}
+	log.Printf("Seed: %d", seed)
+
+	c, err := NewController(s.layout, 0, playlists, playlistName, seed, s.calibrate)
+	if err != nil {
+		return nil, err
+	}
+	if config.Saturation != "" {
+		if err := c.SetSaturationMode(config.Saturation); err != nil {
+			return nil, err
+		}
+	}
+	s.controller = c
 	s.animation = c
 	go c.Run() // The controller has a timer that needs to be started
 
-	return s
+	s.schedule, err = NewScheduler(config.Schedule, c, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.schedule.Update()
+	go s.schedule.Run()
+
+	s.commander = NewCommander(config, client, c, s.favourites)
+	go s.commander.Run()
+
+	return s, nil
 }
 
-// SendFrame sends a frame as binary over MQTT to an ledrx device.
-func (s *Streamer) SendFrame() {
-	s.runtimeMs += s.frameTimeMs
-	f := s.animation.CalculateFrame(s.runtimeMs)
+// SendFrame calculates the frame for a point in time and sends it to the device using the configured Transport.
+func (s *Streamer) SendFrame(runtimeMs int64) {
+	f := s.animation.CalculateFrame(runtimeMs)
 
 	// The animation can opt to not send a frame by returning nil
 	if f != nil {
-		b, _ := f.MarshalBinary()
-		token := s.client.Publish(s.config.Mqtt.Topics.Stream, 0, false, b)
-		token.Wait()
+		s.notifyListeners(f)
+
+		levels := s.correction.Levels(f)
+		s.limiter.Apply(levels)
+		f = s.correction.Quantise(f, levels)
+		if err := s.transport.Send(f); err != nil {
+			log.Printf("Failed to send frame. %s", err)
+		}
+
+		if s.recorder != nil {
+			if err := s.recorder.Record(f); err != nil {
+				log.Printf("Failed to record frame, recording stopped. %s", err)
+				s.recorder.Close()
+				s.recorder = nil
+			}
+		}
+	}
+}
+
+// AddFrameListener registers a function that's given every rendered frame.
+func (s *Streamer) AddFrameListener(listener FrameListener) {
+	s.listenerLock.Lock()
+	defer s.listenerLock.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *Streamer) notifyListeners(f *Frame) {
+	s.listenerLock.Lock()
+	defer s.listenerLock.Unlock()
+	for _, listener := range s.listeners {
+		listener(f)
 	}
 }
 
+// SetRecorder starts recording every frame that's sent.
+func (s *Streamer) SetRecorder(recorder *Recorder) {
+	s.recorder = recorder
+}
+
+// Controller gets the Controller that chooses the animations.
+func (s *Streamer) Controller() *Controller {
+	return s.controller
+}
+
+// Calibrate gets the calibration that can be started and stopped.
+func (s *Streamer) Calibrate() *Calibrate {
+	return s.calibrate
+}
+
+// Favourites gets the favourites that can be saved and recalled.
+func (s *Streamer) Favourites() *Favourites {
+	return s.favourites
+}
+
+// Watts gets the power estimate for the last frame that was sent.
+func (s *Streamer) Watts() float64 {
+	return s.limiter.Watts()
+}
+
+// Stats gets the frame statistics.
+func (s *Streamer) Stats() FrameStats {
+	return s.scheduler.Stats()
+}
+
 // Run causes the Streamer to send Frames continuously.
 func (s *Streamer) Run() {
-	publishTimer := time.NewTicker(time.Duration(s.frameTimeMs) * time.Millisecond)
+	lastReport := time.Now()
+	s.scheduler.Start()
 	for {
-		<-publishTimer.C
-		s.SendFrame()
+		elapsed := s.scheduler.Wait()
+		s.SendFrame(elapsed.Milliseconds())
+		s.scheduler.FrameSent(s.scheduler.Elapsed() - elapsed)
+
+		if time.Since(lastReport) >= statsReportInterval {
+			stats := s.scheduler.Stats()
+			log.Printf("Frames: %d sent, %d dropped, %s max frame time", stats.Sent, stats.Dropped,
+				stats.MaxFrameTime.Round(time.Microsecond))
+			lastReport = time.Now()
+		}
 	}
 }
 
 func (s *Streamer) Subscribe() {
 	// Register for calibration requests
 	s.calibrate.Subscribe()
+
+	// Listen for commands from home automation
+	s.commander.Subscribe()
+
+	// Some transports listen for ACKs from the device
+	if subscriber, ok := s.transport.(Subscriber); ok {
+		subscriber.Subscribe()
+	}
 }
@@ -14,18 +14,21 @@ type RandomStripeGenerator struct {
 	stripeMax     int32
 	minSaturation float64
 	maxSaturation float64
+	rand          *rand.Rand
 }
 
-func NewRandomStripeGenerator(palette []colorful.Color) *RandomStripeGenerator {
+func NewRandomStripeGenerator(r *rand.Rand, palette []colorful.Color) *RandomStripeGenerator {
 	g := new(RandomStripeGenerator)
+	g.rand = r
 	g.palette = palette
 	g.stripeMax = 1000
 	g.stripeMin = 200
 	return g
 }
 
-func NewRandomStripeGeneratorVariableSaturation(minSaturation float64, maxSaturation float64) *RandomStripeGenerator {
+func NewRandomStripeGeneratorVariableSaturation(r *rand.Rand, minSaturation float64, maxSaturation float64) *RandomStripeGenerator {
 	g := new(RandomStripeGenerator)
+	g.rand = r
 	g.stripeMax = 1000
 	g.stripeMin = 200
 	g.minSaturation = minSaturation
@@ -36,11 +39,11 @@ func NewRandomStripeGeneratorVariableSaturation(minSaturation float64, maxSatura
 func (g *RandomStripeGenerator) CreateStripe() Stripe {
 	var colour colorful.Color
 	if g.palette == nil {
-		colour = colorful.Hsl(rand.Float64()*360.0, util.RandomiseSaturation(g.minSaturation, g.maxSaturation), 0.2)
+		colour = colorful.Hsl(g.rand.Float64()*360.0, util.RandomiseSaturation(g.rand, g.minSaturation, g.maxSaturation), 0.2)
 	} else {
 		// Choose a new colour that's different from the previous colour
 		for {
-			newCurrent := rand.Intn(len(g.palette))
+			newCurrent := g.rand.Intn(len(g.palette))
 			if newCurrent != g.current {
 				g.current = newCurrent
 				break
@@ -50,6 +53,6 @@ func (g *RandomStripeGenerator) CreateStripe() Stripe {
 		colour = g.palette[g.current]
 	}
 
-	stripeLength := rand.Int31n(g.stripeMax-g.stripeMin) + g.stripeMin
+	stripeLength := g.rand.Int31n(g.stripeMax-g.stripeMin) + g.stripeMin
 	return Stripe{colour, stripeLength}
 }
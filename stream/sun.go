@@ -0,0 +1,52 @@
+package stream
+
+import (
+	"math"
+	"time"
+)
+
+// sunZenith is the zenith angle at sunrise and sunset in degrees, allowing for refraction and the sun's radius.
+const sunZenith = 90.833
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180.0
+}
+
+func degrees(radians float64) float64 {
+	return radians * 180.0 / math.Pi
+}
+
+// sunTimes calculates sunrise and sunset for the date of day using NOAA's general solar position equations,
+// which are accurate to a minute or two. ok is false when the sun doesn't rise or set on that date.
+func sunTimes(day time.Time, latitude float64, longitude float64) (sunrise time.Time, sunset time.Time, ok bool) {
+	utcMidnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	daysInYear := 365.0
+	if time.Date(day.Year(), time.December, 31, 0, 0, 0, 0, time.UTC).YearDay() == 366 {
+		daysInYear = 366.0
+	}
+
+	// Fractional year, NOAA's (hour-12)/24 term is zero because sunrise and sunset use the sun's position at
+	// midday
+	gamma := 2.0 * math.Pi / daysInYear * float64(utcMidnight.YearDay()-1)
+
+	eqTimeMinutes := 229.18 * (0.000075 + 0.001868*math.Cos(gamma) - 0.032077*math.Sin(gamma) -
+		0.014615*math.Cos(2*gamma) - 0.040849*math.Sin(2*gamma))
+	declination := 0.006918 - 0.399912*math.Cos(gamma) + 0.070257*math.Sin(gamma) -
+		0.006758*math.Cos(2*gamma) + 0.000907*math.Sin(2*gamma) -
+		0.002697*math.Cos(3*gamma) + 0.00148*math.Sin(3*gamma)
+
+	lat := radians(latitude)
+	cosHourAngle := math.Cos(radians(sunZenith))/(math.Cos(lat)*math.Cos(declination)) - math.Tan(lat)*math.Tan(declination)
+	if cosHourAngle < -1.0 || cosHourAngle > 1.0 {
+		// Midnight sun or polar night
+		return time.Time{}, time.Time{}, false
+	}
+	hourAngle := degrees(math.Acos(cosHourAngle))
+
+	sunriseMinutes := 720.0 - 4.0*(longitude+hourAngle) - eqTimeMinutes
+	sunsetMinutes := 720.0 - 4.0*(longitude-hourAngle) - eqTimeMinutes
+
+	sunrise = utcMidnight.Add(time.Duration(sunriseMinutes * float64(time.Minute))).In(day.Location())
+	sunset = utcMidnight.Add(time.Duration(sunsetMinutes * float64(time.Minute))).In(day.Location())
+	return sunrise, sunset, true
+}
@@ -0,0 +1,59 @@
+package stream
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSunTimes(t *testing.T) {
+	bst := time.FixedZone("BST", 1*60*60)
+	gmt := time.FixedZone("GMT", 0)
+	edt := time.FixedZone("EDT", -4*60*60)
+	aedt := time.FixedZone("AEDT", 11*60*60)
+
+	// Published sunrise and sunset times, to the minute
+	for _, tc := range []struct {
+		place     string
+		latitude  float64
+		longitude float64
+		sunrise   time.Time
+		sunset    time.Time
+	}{
+		{"London in summer", 51.5074, -0.1278,
+			time.Date(2026, time.June, 21, 4, 43, 0, 0, bst), time.Date(2026, time.June, 21, 21, 21, 0, 0, bst)},
+		{"London in winter", 51.5074, -0.1278,
+			time.Date(2026, time.December, 21, 8, 4, 0, 0, gmt), time.Date(2026, time.December, 21, 15, 53, 0, 0, gmt)},
+		{"New York in summer", 40.7128, -74.0060,
+			time.Date(2026, time.June, 21, 5, 25, 0, 0, edt), time.Date(2026, time.June, 21, 20, 31, 0, 0, edt)},
+		{"Sydney in summer", -33.8688, 151.2093,
+			time.Date(2026, time.December, 21, 5, 41, 0, 0, aedt), time.Date(2026, time.December, 21, 20, 5, 0, 0, aedt)},
+	} {
+		sunrise, sunset, ok := sunTimes(tc.sunrise, tc.latitude, tc.longitude)
+		if !ok {
+			t.Errorf("%s: the sun should rise and set", tc.place)
+			continue
+		}
+
+		for _, check := range []struct {
+			event    string
+			got      time.Time
+			expected time.Time
+		}{{"sunrise", sunrise, tc.sunrise}, {"sunset", sunset, tc.sunset}} {
+			if diff := check.got.Sub(check.expected); diff < -2*time.Minute || diff > 2*time.Minute {
+				t.Errorf("%s: %s is %s, expected %s", tc.place, check.event,
+					check.got.In(check.expected.Location()).Format("15:04:05"), check.expected.Format("15:04"))
+			}
+		}
+	}
+}
+
+func TestSunTimesInPolarDayAndNight(t *testing.T) {
+	for _, day := range []time.Time{
+		time.Date(2026, time.June, 21, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, time.December, 21, 0, 0, 0, 0, time.UTC),
+	} {
+		if _, _, ok := sunTimes(day, 69.6492, 18.9553); ok {
+			t.Errorf("the sun shouldn't rise and set in Tromsø on %s", day.Format("2 January"))
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package stream
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/fogleman/ease"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+const (
+	defaultTransitionStyle = "hcl"
+	defaultEasing          = "linear"
+
+	// wipeEdge is the fraction of the pixels that are part way through a wipe or dissolve at any moment.
+	wipeEdge = 0.1
+)
+
+// blendFunc blends two frames, progress runs from 0 to 1 after easing.
+type blendFunc func(from *Frame, to *Frame, progress float64) *Frame
+
+// transitionConstructor creates the blend for a transition style, any random choices come from ctx.Rand.
+type transitionConstructor func(ctx *AnimationContext) blendFunc
+
+var transitionStyles = map[string]transitionConstructor{
+	"hcl":      crossfade(colorful.Color.BlendHcl),
+	"rgb":      crossfade(colorful.Color.BlendRgb),
+	"linear":   crossfade(blendLinearRgb),
+	"lab":      crossfade(colorful.Color.BlendLab),
+	"wipe":     newIndexWipe,
+	"spatial":  newSpatialWipe,
+	"dissolve": newDissolve,
+	"black":    func(ctx *AnimationContext) blendFunc { return fadeThroughBlack },
+}
+
+var easings = map[string]ease.Function{
+	"linear":       ease.Linear,
+	"inQuad":       ease.InQuad,
+	"outQuad":      ease.OutQuad,
+	"inOutQuad":    ease.InOutQuad,
+	"inCubic":      ease.InCubic,
+	"outCubic":     ease.OutCubic,
+	"inOutCubic":   ease.InOutCubic,
+	"inSine":       ease.InSine,
+	"outSine":      ease.OutSine,
+	"inOutSine":    ease.InOutSine,
+	"inExpo":       ease.InExpo,
+	"outExpo":      ease.OutExpo,
+	"inOutExpo":    ease.InOutExpo,
+	"inCirc":       ease.InCirc,
+	"outCirc":      ease.OutCirc,
+	"inOutCirc":    ease.InOutCirc,
+	"outBack":      ease.OutBack,
+	"outBounce":    ease.OutBounce,
+	"inOutElastic": ease.InOutElastic,
+}
+
+// TransitionStyles gets the names of the transition styles sorted by name.
+func TransitionStyles() []string {
+	names := make([]string, 0, len(transitionStyles))
+	for name := range transitionStyles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Easings gets the names of the easing curves sorted by name.
+func Easings() []string {
+	names := make([]string, 0, len(easings))
+	for name := range easings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ValidateTransition checks that a transition style and easing exist, empty names are the defaults.
+func ValidateTransition(style string, easing string) error {
+	if _, ok := transitionStyles[style]; style != "" && !ok {
+		return fmt.Errorf("unknown transition style %q", style)
+	}
+
+	if _, ok := easings[easing]; easing != "" && !ok {
+		return fmt.Errorf("unknown easing %q", easing)
+	}
+
+	return nil
+}
+
+// A Transition blends the frames from one animation into another.
+type Transition struct {
+	blend blendFunc
+	ease  ease.Function
+}
+
+// NewTransition creates a Transition, empty names are the defaults.
+func NewTransition(style string, easing string, ctx *AnimationContext) (*Transition, error) {
+	if err := ValidateTransition(style, easing); err != nil {
+		return nil, err
+	}
+
+	if style == "" {
+		style = defaultTransitionStyle
+	}
+	if easing == "" {
+		easing = defaultEasing
+	}
+
+	t := new(Transition)
+	t.blend = transitionStyles[style](ctx)
+	t.ease = easings[easing]
+	return t, nil
+}
+
+// Blend mixes two frames, progress runs from 0 (all from) to 1 (all to).
+func (t *Transition) Blend(from *Frame, to *Frame, progress float64) *Frame {
+	return t.blend(from, to, t.ease(progress))
+}
+
+// crossfade blends every pixel by the same amount using a colour space's blend.
+func crossfade(blend func(c1 colorful.Color, c2 colorful.Color, t float64) colorful.Color) transitionConstructor {
+	return func(ctx *AnimationContext) blendFunc {
+		return func(from *Frame, to *Frame, progress float64) *Frame {
+			out := NewFrame(from.layout)
+			for i := range out.pixels {
+				out.pixels[i] = blend(from.pixels[i], to.pixels[i], progress)
+			}
+
+			return out
+		}
+	}
+}
+
+// blendLinearRgb blends in linear light, which keeps the brightness steady better than gamma encoded RGB.
+func blendLinearRgb(c1 colorful.Color, c2 colorful.Color, t float64) colorful.Color {
+	r1, g1, b1 := c1.LinearRgb()
+	r2, g2, b2 := c2.LinearRgb()
+	return colorful.LinearRgb(r1+t*(r2-r1), g1+t*(g2-g1), b1+t*(b2-b1))
+}
+
+func fadeThroughBlack(from *Frame, to *Frame, progress float64) *Frame {
+	if progress < 0.5 {
+		return from.scale(1.0 - (progress * 2.0))
+	}
+
+	return to.scale((progress - 0.5) * 2.0)
+}
+
+// thresholdBlend switches each pixel when the progress passes its threshold, thresholds run from 0 to 1.
+// Pixels near the threshold are part way between the frames so that the edge is soft.
+func thresholdBlend(thresholds []float64) blendFunc {
+	return func(from *Frame, to *Frame, progress float64) *Frame {
+		out := NewFrame(from.layout)
+		position := progress * (1.0 + wipeEdge)
+		for i := range out.pixels {
+			amount := math.Max(0.0, math.Min(1.0, (position-thresholds[i])/wipeEdge))
+			out.pixels[i] = from.pixels[i].BlendHcl(to.pixels[i], amount).Clamped()
+		}
+
+		return out
+	}
+}
+
+func newIndexWipe(ctx *AnimationContext) blendFunc {
+	thresholds := make([]float64, ctx.Layout.Pixels)
+	for i := range thresholds {
+		thresholds[i] = float64(i) / float64(ctx.Layout.Pixels)
+	}
+
+	return thresholdBlend(thresholds)
+}
+
+func newDissolve(ctx *AnimationContext) blendFunc {
+	thresholds := make([]float64, ctx.Layout.Pixels)
+	for i, p := range ctx.Rand.Perm(ctx.Layout.Pixels) {
+		thresholds[i] = float64(p) / float64(ctx.Layout.Pixels)
+	}
+
+	return thresholdBlend(thresholds)
+}
+
+// newSpatialWipe wipes from the top of the tree to the bottom using the pixel map. It falls back to a wipe
+// along the string when the lights haven't been calibrated.
+func newSpatialWipe(ctx *AnimationContext) blendFunc {
+	if ctx.PixelMap == nil || ctx.PixelMap.Len() != ctx.Layout.Pixels {
+		return newIndexWipe(ctx)
+	}
+
+	thresholds := make([]float64, ctx.Layout.Pixels)
+	for i := range thresholds {
+		thresholds[i] = ctx.PixelMap.Normalised(i).Y
+	}
+
+	return thresholdBlend(thresholds)
+}
@@ -0,0 +1,43 @@
+package stream
+
+import (
+	"fmt"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// An AckHandler is called when a device acknowledges that it has displayed a frame.
+type AckHandler func(ackID uint8)
+
+// A Transport delivers frames to a device and reports any acknowledgements that come back.
+type Transport interface {
+	Send(f *Frame) error
+	SetAckHandler(handler AckHandler)
+}
+
+// A Subscriber is a Transport that needs to (re)subscribe to topics whenever the MQTT client connects.
+type Subscriber interface {
+	Subscribe()
+}
+
+// NewTransport creates the Transport selected in the config.
+func NewTransport(config Config, client mqtt.Client, layout *Layout) (Transport, error) {
+	switch config.Transport.Type {
+	case "", "mqtt":
+		return NewMqttTransport(config, client), nil
+	case "udp":
+		return NewUdpTransport(config.Transport.Udp.Address)
+	case "file":
+		return NewFileTransport(config.Transport.File.Path)
+	case "ddp":
+		return NewDdpTransport(config.Transport.Ddp.Address)
+	case "e131":
+		return NewE131Transport(config, layout)
+	case "artnet":
+		return NewArtNetTransport(config, layout)
+	case "memory":
+		return NewMemoryTransport(), nil
+	}
+
+	return nil, fmt.Errorf("unknown transport type %q", config.Transport.Type)
+}
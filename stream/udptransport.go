@@ -0,0 +1,38 @@
+package stream
+
+import (
+	"net"
+)
+
+// UdpTransport sends each frame, in the same binary format used over MQTT, as a single UDP datagram.
+// The device isn't expected to ACK frames.
+type UdpTransport struct {
+	conn net.Conn
+}
+
+// NewUdpTransport creates an instance of a UdpTransport that sends to a host:port address.
+func NewUdpTransport(address string) (*UdpTransport, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	t := new(UdpTransport)
+	t.conn = conn
+	return t, nil
+}
+
+// Send writes a frame to the socket.
+func (t *UdpTransport) Send(f *Frame) error {
+	b, err := f.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	_, err = t.conn.Write(b)
+	return err
+}
+
+// SetAckHandler does nothing because ACKs aren't supported over UDP.
+func (t *UdpTransport) SetAckHandler(handler AckHandler) {
+}
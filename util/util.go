@@ -6,8 +6,8 @@ import (
 	"github.com/fogleman/ease"
 )
 
-func RandomiseSaturation(min float64, max float64) float64 {
-	return rand.Float64()*(max-min) + min
+func RandomiseSaturation(r *rand.Rand, min float64, max float64) float64 {
+	return r.Float64()*(max-min) + min
 }
 
 type Memoizer map[int][]float64